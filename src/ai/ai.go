@@ -12,77 +12,233 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	iaServices = []string{"GEMINI", "MISTRAL", "OPENAI"}
 )
 
+// local servers with an OpenAI compatible api, no key
+var localServers = map[string]string{
+	"ollama":   "http://localhost:11434/v1",
+	"llamacpp": "http://localhost:8080/v1",
+}
+
+// Settings of the `ai:` configuration key
+// a plain string is the provider, as `ai: ollama` or `ai: MISTRAL_API_KEY`
+type Settings struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model,omitempty"`
+	URL      string `yaml:"url,omitempty"`
+	KeyEnv   string `yaml:"key_env,omitempty"`
+	// responses kept in cache, as "720h"
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+	// template files, by name: "package", "summary"
+	Prompts map[string]string `yaml:"prompts,omitempty"`
+}
+
+func (s *Settings) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = Settings{Provider: value.Value}
+		return nil
+	}
+	type plain Settings
+	return value.Decode((*plain)(s))
+}
+
+// normalize old values, as "GEMINI_API_KEY"
+func (s Settings) normalize() Settings {
+	if provider, found := strings.CutSuffix(strings.ToUpper(s.Provider), "_API_KEY"); found {
+		if s.KeyEnv == "" {
+			s.KeyEnv = strings.ToUpper(s.Provider)
+		}
+		s.Provider = provider
+	}
+	s.Provider = strings.ToLower(strings.ReplaceAll(s.Provider, ".", ""))
+	return s
+}
+
 type (
 	IaiBase interface {
-		Set()
-		Ask(prompt string) string
+		Set(settings Settings)
+		Ask(prompt string) (string, error)
+		Id() string
 	}
 
 	AiBase struct {
-		url   string
-		Model string
-		key   string
+		provider string
+		url      string
+		Model    string
+		key      string
 	}
 	IaiGemini  struct{ AiBase }
 	IaiMistral struct{ AiBase }
 	IaiOpenai  struct{ AiBase }
 )
 
-func (a *AiBase) Ask(prompt string) string {
-	return ""
+func (a *AiBase) Ask(prompt string) (string, error) {
+	return "", nil
 }
 
-// -------------------------------------------------
+// Id as "provider/model"
+func (a *AiBase) Id() string {
+	return a.provider + "/" + a.Model
+}
 
-func MakeAi(key string) (ai IaiBase) {
+// APIError: http error of an ai service, with the message of the api
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Status     string
+	Message    string
+	RetryAfter string
+}
 
-	var test = func(key string) IaiBase {
-		k := os.Getenv(key)
-		if k != "" {
-			if strings.Contains(key, "GEMINI") {
-				ai = &IaiGemini{}
-				ai.Set()
-				return ai
-			}
-			if strings.Contains(key, "MISTRAL") {
-				ai = &IaiMistral{}
-				ai.Set()
-				return ai
-			}
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Provider, e.Status)
+	if e.StatusCode == http.StatusTooManyRequests {
+		msg += " (rate limit"
+		if e.RetryAfter != "" {
+			msg += ", retry after " + e.RetryAfter + "s"
 		}
-		return nil
+		msg += ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
 	}
-	key = strings.ToUpper(key)
-	ai = nil
-	if key != "" {
-		if k := os.Getenv(key); k != "" {
-			return test(key)
+	return msg
+}
+
+// apiMessage extracts the error message of a response body
+func apiMessage(body []byte) string {
+	var response struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(response.Error, &nested) == nil && nested.Message != "":
+			return nested.Message
+		case json.Unmarshal(response.Error, &text) == nil && text != "":
+			return text
+		case response.Message != "":
+			return response.Message
+		case len(response.Detail) > 0:
+			return string(response.Detail)
 		}
 	}
-	for _, service := range iaServices {
-		t := test(service + "_API_KEY")
-		if t != nil {
-			return t
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 200 {
+		msg = msg[:200] + "…"
+	}
+	return msg
+}
+
+// post json request, returns the body of a 200 response
+func (a *AiBase) post(url string, request any, headers map[string]string) ([]byte, error) {
+	askBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(askBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", a.provider, err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", a.provider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{a.provider, resp.StatusCode, resp.Status, apiMessage(bodyBytes), resp.Header.Get("Retry-After")}
+	}
+	return bodyBytes, nil
+}
+
+// configure replaces defaults by settings
+func (a *AiBase) configure(settings Settings, keyEnv string) {
+	a.provider = settings.Provider
+	if settings.URL != "" {
+		a.url = strings.TrimSuffix(settings.URL, "/")
+	}
+	if settings.Model != "" {
+		a.Model = settings.Model
+	}
+	if settings.KeyEnv != "" {
+		keyEnv = settings.KeyEnv
+	}
+	if keyEnv != "" {
+		a.key = os.Getenv(keyEnv)
+	}
+}
+
+// -------------------------------------------------
+
+// MakeAi returns the configured backend, nil if not usable (no key)
+// without provider, the first service with a key in environment
+func MakeAi(settings Settings) (ai IaiBase) {
+	settings = settings.normalize()
+	if settings.Provider == "" {
+		for _, service := range iaServices {
+			if os.Getenv(service+"_API_KEY") != "" {
+				return MakeAi(Settings{Provider: service, Model: settings.Model, URL: settings.URL})
+			}
 		}
+		return nil
+	}
+
+	keyEnv := strings.ToUpper(settings.Provider) + "_API_KEY"
+	if settings.KeyEnv != "" {
+		keyEnv = settings.KeyEnv
+	}
+	_, local := localServers[settings.Provider]
+	if !local && settings.URL == "" && os.Getenv(keyEnv) == "" {
+		// cloud service without key
+		return nil
 	}
+
+	switch settings.Provider {
+	case "gemini":
+		ai = &IaiGemini{}
+	case "mistral":
+		ai = &IaiMistral{}
+	default:
+		// openai, ollama, llamacpp or any OpenAI compatible server
+		ai = &IaiOpenai{}
+	}
+	ai.Set(settings)
 	return ai
 }
 
 // -------------------------------------------------
 
-func (a *IaiGemini) Set() {
-	a.url = "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=%s"
+func (a *IaiGemini) Set(settings Settings) {
+	a.url = "https://generativelanguage.googleapis.com/v1beta"
 	a.Model = "gemini-2.0-flash"
-	a.key = os.Getenv("GEMINI_API_KEY")
+	a.configure(settings, "GEMINI_API_KEY")
 }
 
-func (a IaiGemini) Ask(prompt string) string {
+func (a IaiGemini) Ask(prompt string) (string, error) {
 	//fmt.Println("#", a.Model)
 
 	type (
@@ -121,7 +277,8 @@ func (a IaiGemini) Ask(prompt string) string {
 		}
 	)
 
-	url := fmt.Sprintf(a.url, a.key)
+	// key in header, not in url displayed by errors
+	url := a.url + "/models/" + a.Model + ":generateContent"
 
 	ask := map[string]interface{}{
 		"contents": []map[string]interface{}{
@@ -134,49 +291,31 @@ func (a IaiGemini) Ask(prompt string) string {
 			},
 		},
 	}
-	askBytes, _ := json.Marshal(ask)
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(askBytes))
-	if err != nil {
-		return ""
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, err := a.post(url, ask, map[string]string{"x-goog-api-key": a.key})
 	if err != nil {
-		return ""
+		return "", err
 	}
 	var response GeminiResponse
-	err = json.Unmarshal(bodyBytes, &response)
-	if err != nil {
-		return ""
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return "", fmt.Errorf("%s: %w", a.provider, err)
 	}
 
 	if len(response.Candidates) > 0 && len(response.Candidates[0].Content.Parts) > 0 {
-		return response.Candidates[0].Content.Parts[0].Text
+		return response.Candidates[0].Content.Parts[0].Text, nil
 	}
 
-	return ""
+	return "", fmt.Errorf("%s: %s", a.provider, "empty response")
 }
 
 // -------------------------------------------------
 
-func (a *IaiMistral) Set() {
-	a.url = "https://api.mistral.ai/v1/chat/completions"
+func (a *IaiMistral) Set(settings Settings) {
+	a.url = "https://api.mistral.ai/v1"
 	a.Model = "mistral-large-latest"
-	a.key = os.Getenv("MISTRAL_API_KEY")
+	a.configure(settings, "MISTRAL_API_KEY")
 }
 
-func (a IaiMistral) Ask(prompt string) string {
+func (a IaiMistral) Ask(prompt string) (string, error) {
 	//fmt.Println("#", a.Model)
 
 	type (
@@ -219,55 +358,81 @@ func (a IaiMistral) Ask(prompt string) string {
 			{Role: "system", Content: "response on one line"},
 		},
 	}
-	askBytes, _ := json.Marshal(ask)
-
-	req, err := http.NewRequest("POST", a.url, bytes.NewBuffer(askBytes))
-	if err != nil {
-		return ""
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.key))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, err := a.post(a.url+"/chat/completions", ask, map[string]string{"Authorization": "Bearer " + a.key})
 	if err != nil {
-		return ""
+		return "", err
 	}
 
 	var response MistralResponse
-	err = json.Unmarshal(bodyBytes, &response)
-	if err != nil {
-		return ""
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return "", fmt.Errorf("%s: %w", a.provider, err)
 	}
 
 	if len(response.Choices) > 0 {
-		return response.Choices[0].Message.Content
+		return response.Choices[0].Message.Content, nil
 	}
 
-	return ""
+	return "", fmt.Errorf("%s: %s", a.provider, "empty response")
 }
 
 // -------------------------------------------------
 
-func GetAskPackage(pkgname, repo string) string {
+// IaiOpenai: OpenAI chat completions api, also ollama, llama.cpp server ...
+func (a *IaiOpenai) Set(settings Settings) {
+	a.url = "https://api.openai.com/v1"
+	a.Model = "gpt-4o-mini"
+	keyEnv := "OPENAI_API_KEY"
+	if url, ok := localServers[settings.Provider]; ok {
+		a.url = url
+		a.Model = "llama3.2"
+		keyEnv = ""
+	}
+	a.configure(settings, keyEnv)
+}
+
+func (a IaiOpenai) Ask(prompt string) (string, error) {
 
-	req := `
-		Use this lang ` + os.Getenv("LANG") + ` for the response.
-		Response not in markdown but in simple text for console, text/plain.
+	type (
+		ChatMessage struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}
+		ChatRequest struct {
+			Model    string        `json:"model"`
+			Messages []ChatMessage `json:"messages"`
+		}
+		Choice struct {
+			Index        int         `json:"index"`
+			Message      ChatMessage `json:"message"`
+			FinishReason string      `json:"finish_reason"`
+		}
+		ChatResponse struct {
+			Model   string   `json:"model"`
+			Choices []Choice `json:"choices"`
+		}
+	)
 
-		Informations on a pacman package in Manjaro linux, or in archlinux.
-		This package is in repository : ` + repo + `
+	ask := ChatRequest{
+		Model: a.Model,
+		Messages: []ChatMessage{
+			{Role: "user", Content: prompt},
+		},
+	}
+	headers := map[string]string{}
+	if a.key != "" {
+		headers["Authorization"] = "Bearer " + a.key
+	}
+	bodyBytes, err := a.post(a.url+"/chat/completions", ask, headers)
+	if err != nil {
+		return "", err
+	}
 
-		Informations on utility of this manjaro package : ` + pkgname + `
-		If this package have an application, can you add a descriptif of this app ? Maximum 10 lines.
-	`
-	return req
+	var response ChatResponse
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return "", fmt.Errorf("%s: %w", a.provider, err)
+	}
+	if len(response.Choices) > 0 {
+		return response.Choices[0].Message.Content, nil
+	}
+	return "", fmt.Errorf("%s: %s", a.provider, "empty response")
 }
@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a provider and its api, as seen by the fake server
+type providerTest struct {
+	provider string
+	path     string // path of the request, model included for gemini
+	header   string // header with the key
+	value    string // expected value of header
+	ok       string // body of a 200 response, with "hello"
+	apiError string // body of an error, with "bad model"
+}
+
+var providerTests = []providerTest{
+	{
+		provider: "gemini",
+		path:     "/models/test-model:generateContent",
+		header:   "x-goog-api-key",
+		value:    "secret",
+		ok:       `{"candidates":[{"content":{"parts":[{"text":"hello"}],"role":"model"}}]}`,
+		apiError: `{"error":{"code":400,"message":"bad model","status":"INVALID_ARGUMENT"}}`,
+	},
+	{
+		provider: "mistral",
+		path:     "/chat/completions",
+		header:   "Authorization",
+		value:    "Bearer secret",
+		ok:       `{"choices":[{"index":0,"message":{"role":"assistant","content":"hello"}}]}`,
+		apiError: `{"object":"error","message":"bad model","type":"invalid_model"}`,
+	},
+	{
+		provider: "openai",
+		path:     "/chat/completions",
+		header:   "Authorization",
+		value:    "Bearer secret",
+		ok:       `{"model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"hello"}}]}`,
+		apiError: `{"error":{"message":"bad model","type":"invalid_request_error"}}`,
+	},
+	{
+		provider: "ollama",
+		path:     "/chat/completions",
+		ok:       `{"model":"test-model","choices":[{"index":0,"message":{"role":"assistant","content":"hello"}}]}`,
+		apiError: `{"error":"bad model"}`,
+	},
+}
+
+// fakeServer answers with status and body, and checks the request of the provider
+func fakeServer(t *testing.T, test providerTest, status int, body string, headers map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != test.path {
+			t.Errorf("%s: request %s %s, want POST %s", test.provider, r.Method, r.URL.Path, test.path)
+		}
+		if test.header != "" && r.Header.Get(test.header) != test.value {
+			t.Errorf("%s: header %s = %q, want %q", test.provider, test.header, r.Header.Get(test.header), test.value)
+		}
+		if strings.Contains(r.URL.RawQuery, "secret") {
+			t.Errorf("%s: key in url %s", test.provider, r.URL)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if !json.Valid(data) || !strings.Contains(string(data), "the prompt") {
+			t.Errorf("%s: bad request body %s", test.provider, data)
+		}
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func makeTestAi(t *testing.T, test providerTest, url string) IaiBase {
+	t.Helper()
+	t.Setenv("MBC_TEST_KEY", "secret")
+	a := MakeAi(Settings{Provider: test.provider, Model: "test-model", URL: url, KeyEnv: "MBC_TEST_KEY"})
+	if a == nil {
+		t.Fatalf("%s: no backend", test.provider)
+	}
+	return a
+}
+
+func TestAskOK(t *testing.T) {
+	for _, test := range providerTests {
+		server := fakeServer(t, test, http.StatusOK, test.ok, nil)
+		response, err := makeTestAi(t, test, server.URL).Ask("the prompt")
+		if err != nil || response != "hello" {
+			t.Errorf("%s: Ask = %q, %v, want \"hello\"", test.provider, response, err)
+		}
+	}
+}
+
+func TestAskAPIError(t *testing.T) {
+	for _, test := range providerTests {
+		server := fakeServer(t, test, http.StatusBadRequest, test.apiError, nil)
+		_, err := makeTestAi(t, test, server.URL).Ask("the prompt")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: error %v, want an APIError", test.provider, err)
+			continue
+		}
+		if apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != "bad model" {
+			t.Errorf("%s: APIError %d %q, want 400 \"bad model\"", test.provider, apiErr.StatusCode, apiErr.Message)
+		}
+		if !strings.Contains(err.Error(), "bad model") || !strings.HasPrefix(err.Error(), test.provider+":") {
+			t.Errorf("%s: error message %q", test.provider, err)
+		}
+	}
+}
+
+func TestAskRateLimit(t *testing.T) {
+	for _, test := range providerTests {
+		server := fakeServer(t, test, http.StatusTooManyRequests, test.apiError, map[string]string{"Retry-After": "30"})
+		_, err := makeTestAi(t, test, server.URL).Ask("the prompt")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Errorf("%s: error %v, want an APIError", test.provider, err)
+			continue
+		}
+		if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != "30" {
+			t.Errorf("%s: APIError %d retry %q, want 429 retry \"30\"", test.provider, apiErr.StatusCode, apiErr.RetryAfter)
+		}
+		if !strings.Contains(err.Error(), "rate limit") || !strings.Contains(err.Error(), "30s") {
+			t.Errorf("%s: error message %q", test.provider, err)
+		}
+	}
+}
+
+func TestAskMalformed(t *testing.T) {
+	for _, test := range providerTests {
+		for _, body := range []string{`{"choices": [`, `<html>gateway</html>`, `{}`} {
+			server := fakeServer(t, test, http.StatusOK, body, nil)
+			response, err := makeTestAi(t, test, server.URL).Ask("the prompt")
+			if err == nil {
+				t.Errorf("%s: body %q: response %q, want an error", test.provider, body, response)
+			} else if !strings.HasPrefix(err.Error(), test.provider+":") {
+				t.Errorf("%s: error message %q", test.provider, err)
+			}
+		}
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`{"error":{"message":"nested"}}`, "nested"},
+		{`{"error":"text"}`, "text"},
+		{`{"message":"top"}`, "top"},
+		{`{"detail":"not found"}`, `"not found"`},
+		{`plain text`, "plain text"},
+		{strings.Repeat("x", 300), strings.Repeat("x", 200) + "…"},
+	}
+	for _, test := range tests {
+		if got := apiMessage([]byte(test.body)); got != test.expected {
+			t.Errorf("apiMessage(%q) = %q, want %q", test.body, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL: responses are kept 30 days
+const DefaultCacheTTL = 30 * 24 * time.Hour
+
+// Cache of responses, one file by question in Dir
+type Cache struct {
+	Dir     string
+	TTL     time.Duration
+	Refresh bool // ask again, replace the cached response
+}
+
+// key of a response: provider/model, package, version, lang and prompt
+func (c Cache) file(keys ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\x00")))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:16])+".txt")
+}
+
+func (c Cache) get(file string) (string, bool) {
+	if c.Refresh {
+		return "", false
+	}
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) > c.TTL {
+		return "", false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+func (c Cache) set(file, response string) error {
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	// a temporary file of its own: two runs can ask the same question
+	tmp, err := os.CreateTemp(c.Dir, filepath.Base(file)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(response)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// AskCached returns the cached response or asks the ai,
+// keys identify the question, as package name and version
+func AskCached(ai IaiBase, cache Cache, prompt string, keys ...string) (string, error) {
+	if cache.TTL <= 0 {
+		cache.TTL = DefaultCacheTTL
+	}
+	file := cache.file(append([]string{ai.Id(), os.Getenv("LANG"), prompt}, keys...)...)
+	if response, ok := cache.get(file); ok {
+		return response, nil
+	}
+	response, err := ai.Ask(prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := cache.set(file, response); err != nil {
+		// the response is good, only the next question is not cached
+		fmt.Fprintln(os.Stderr, "WARNING!", err)
+	}
+	return response, nil
+}
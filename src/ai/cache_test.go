@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAi counts the questions
+type fakeAi struct {
+	id    string
+	asked int
+	err   error
+}
+
+func (a *fakeAi) Set(settings Settings) {}
+
+func (a *fakeAi) Ask(prompt string) (string, error) {
+	a.asked++
+	if a.err != nil {
+		return "", a.err
+	}
+	return a.id + " " + prompt, nil
+}
+
+func (a *fakeAi) Id() string {
+	return a.id
+}
+
+func TestAskCached(t *testing.T) {
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	cache := Cache{Dir: t.TempDir()}
+	ai := &fakeAi{id: "openai/gpt"}
+
+	ask := func(ai *fakeAi, version string, asked int) {
+		t.Helper()
+		before := ai.asked
+		response, err := AskCached(ai, cache, "prompt", "mesa", version)
+		if err != nil || response != ai.id+" prompt" {
+			t.Fatalf("AskCached = %q, %v", response, err)
+		}
+		if ai.asked-before != asked {
+			t.Errorf("%s mesa %s: asked %d times, want %d", ai.id, version, ai.asked-before, asked)
+		}
+	}
+
+	ask(ai, "25.0.1-1", 1) // miss
+	ask(ai, "25.0.1-1", 0) // hit
+	ask(ai, "25.0.2-1", 1) // other version
+	ask(&fakeAi{id: "openai/gpt-4o"}, "25.0.1-1", 1)
+	ask(&fakeAi{id: "mistral/gpt"}, "25.0.1-1", 1)
+
+	t.Setenv("LANG", "es_ES.UTF-8")
+	ask(ai, "25.0.1-1", 1) // other lang
+	ask(ai, "25.0.1-1", 0)
+
+	if response, _ := AskCached(ai, cache, "prompt", "glibc", "25.0.1-1"); ai.asked != 4 || response == "" {
+		t.Errorf("other package: asked %d times, want 4", ai.asked)
+	}
+
+	cache.Refresh = true
+	ask(ai, "25.0.1-1", 1)
+}
+
+func TestAskCachedTTL(t *testing.T) {
+	cache := Cache{Dir: t.TempDir(), TTL: time.Hour}
+	ai := &fakeAi{id: "openai/gpt"}
+
+	AskCached(ai, cache, "prompt", "mesa", "1.0-1")
+	files, _ := filepath.Glob(filepath.Join(cache.Dir, "*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("%d files in cache, want 1", len(files))
+	}
+
+	old := time.Now().Add(-30 * time.Minute)
+	os.Chtimes(files[0], old, old)
+	AskCached(ai, cache, "prompt", "mesa", "1.0-1")
+	if ai.asked != 1 {
+		t.Errorf("before TTL: asked %d times, want 1", ai.asked)
+	}
+
+	old = time.Now().Add(-2 * time.Hour)
+	os.Chtimes(files[0], old, old)
+	AskCached(ai, cache, "prompt", "mesa", "1.0-1")
+	if ai.asked != 2 {
+		t.Errorf("after TTL: asked %d times, want 2", ai.asked)
+	}
+
+	// without TTL, DefaultCacheTTL
+	cache.TTL = 0
+	old = time.Now().Add(-DefaultCacheTTL + time.Hour)
+	os.Chtimes(files[0], old, old)
+	AskCached(ai, cache, "prompt", "mesa", "1.0-1")
+	if ai.asked != 2 {
+		t.Errorf("default TTL: asked %d times, want 2", ai.asked)
+	}
+}
+
+func TestAskCachedErrors(t *testing.T) {
+	cache := Cache{Dir: t.TempDir()}
+	ai := &fakeAi{id: "openai/gpt", err: errors.New("openai: 500")}
+	if _, err := AskCached(ai, cache, "prompt", "mesa"); err == nil {
+		t.Error("error of ai not returned")
+	}
+	if files, _ := filepath.Glob(filepath.Join(cache.Dir, "*")); len(files) > 0 {
+		t.Errorf("error cached: %v", files)
+	}
+
+	// cache not writable: the response is returned
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0o644)
+	ai.err = nil
+	response, err := AskCached(ai, Cache{Dir: filepath.Join(file, "ai")}, "prompt", "mesa")
+	if err != nil || response == "" {
+		t.Errorf("cache not writable: %q, %v", response, err)
+	}
+}
+
+func TestAskCachedConcurrent(t *testing.T) {
+	cache := Cache{Dir: t.TempDir()}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			AskCached(&fakeAi{id: "openai/gpt"}, cache, "prompt", "mesa")
+		}()
+	}
+	wg.Wait()
+
+	files, _ := filepath.Glob(filepath.Join(cache.Dir, "*"))
+	if len(files) != 1 {
+		t.Fatalf("files in cache: %v, want one response", files)
+	}
+	if data, _ := os.ReadFile(files[0]); string(data) != "openai/gpt prompt" {
+		t.Errorf("cached response %q", data)
+	}
+}
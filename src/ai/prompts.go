@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"os"
+	"strings"
+	"text/template"
+)
+
+// PackagePrompt: default template of `info --ai`, data of a package
+const PackagePrompt = `Use this lang {{.Lang}} for the response.
+Response not in markdown but in simple text for console, text/plain.
+
+Informations on a pacman package in Manjaro linux, or in archlinux.
+This package is in repository : {{.Repo}}
+
+Informations on utility of this manjaro package : {{.Name}}
+{{if .Desc}}Description: {{.Desc}}
+{{end}}{{if .URL}}Project: {{.URL}}
+{{end}}{{if .Licenses}}Licenses: {{join .Licenses ", "}}
+{{end}}{{if .Depends}}Depends on: {{join .Depends ", "}}
+{{end}}{{if .Versions}}Versions:
+{{range .Versions}}  - {{.Branch}}: {{.Version}}
+{{end}}{{end}}
+Only use these informations and what you know for sure about this project.
+If this package have an application, can you add a descriptif of this app ? Maximum 10 lines.
+`
+
+// SummaryPrompt: default template of `summarize`, data of a branch update
+const SummaryPrompt = `Use this lang {{.Lang}} for the response.
+Response not in markdown but in simple text for a forum post.
+
+Write the announcement of a Manjaro linux update: packages of branch "{{.From}}" updated to branch "{{.To}}".
+Start with a short overview of the most important changes, then list the highlights.
+Do not invent packages or versions: only use the data below.
+{{if .Kernels}}
+Kernels:
+{{range .Kernels}}  - {{.Name}} {{.OldVersion}} -> {{.Version}}
+{{end}}{{end}}{{if .Majors}}
+Major upgrades:
+{{range .Majors}}  - {{.Name}} {{.OldVersion}} -> {{.Version}} ({{.Change}}){{if .Desc}}: {{.Desc}}{{end}}
+{{end}}{{end}}{{if .Minors}}
+Minor upgrades:
+{{range .Minors}}  - {{.Name}} {{.OldVersion}} -> {{.Version}}
+{{end}}{{end}}{{if .New}}
+New packages:
+{{range .New}}  - {{.Name}} {{.Version}}{{if .Desc}}: {{.Desc}}{{end}}
+{{end}}{{end}}{{if .Renamed}}
+Renamed or replaced packages:
+{{range .Renamed}}  - {{.OldName}} -> {{.Name}}
+{{end}}{{end}}{{if .Removed}}
+Removed packages:
+{{range .Removed}}  - {{.Name}}
+{{end}}{{end}}
+Number of upgrades by kind of change:
+{{range $change, $count := .Counts}}  - {{$change}}: {{$count}}
+{{end}}`
+
+// LoadPrompt returns the template in file, or the default template
+func LoadPrompt(file, defaultPrompt string) (string, error) {
+	if file == "" {
+		return defaultPrompt, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Render a prompt template (text/template) with data
+func Render(prompt string, data any) (string, error) {
+	tmpl, err := template.New("prompt").Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+	}).Parse(prompt)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,144 @@
+package alpm
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kinds of change between two versions of a package, most important first
+const (
+	ChangeEpoch      = "epoch"
+	ChangeMajor      = "major"
+	ChangeMinor      = "minor"
+	ChangePatch      = "patch"
+	ChangePrerelease = "prerelease"
+	ChangePkgrel     = "pkgrel"  // same upstream version, pkgrel bump
+	ChangeRebuild    = "rebuild" // same version, other build date
+	ChangeNone       = ""
+)
+
+var Changes = []string{ChangeEpoch, ChangeMajor, ChangeMinor, ChangePatch, ChangePrerelease, ChangePkgrel, ChangeRebuild}
+
+// Version components of "epoch:upstream-pkgrel"
+type Version struct {
+	Epoch    string
+	Upstream string
+	Pkgrel   string
+}
+
+func ParseVersion(version string) Version {
+	epoch, upstream, pkgrel, _ := parseEVR(version)
+	return Version{epoch, upstream, pkgrel}
+}
+
+// segments of an upstream version: numeric and alpha parts, without separators
+// "6.14.0rc7" -> 6 14 0 rc 7
+func versionSegments(upstream string) (segments []string) {
+	current := ""
+	digit := false
+	for _, r := range upstream {
+		switch {
+		case unicode.IsDigit(r) || unicode.IsLetter(r):
+			if current != "" && unicode.IsDigit(r) != digit {
+				segments = append(segments, current)
+				current = ""
+			}
+			current += string(r)
+			digit = unicode.IsDigit(r)
+		default:
+			if current != "" {
+				segments = append(segments, current)
+				current = ""
+			}
+		}
+	}
+	if current != "" {
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+func isAlpha(segment string) bool {
+	return segment != "" && !unicode.IsDigit(rune(segment[0]))
+}
+
+// ClassifyChange returns the kind of change between versions a and b,
+// ChangeRebuild is only detected by packages (build dates), see ClassifyPackages
+func ClassifyChange(a, b string) string {
+	va, vb := ParseVersion(a), ParseVersion(b)
+	if rpmvercmp(va.Epoch, vb.Epoch) != 0 {
+		return ChangeEpoch
+	}
+	if rpmvercmp(va.Upstream, vb.Upstream) == 0 {
+		if va.Pkgrel != vb.Pkgrel {
+			return ChangePkgrel
+		}
+		return ChangeNone
+	}
+
+	sa, sb := versionSegments(strings.ToLower(va.Upstream)), versionSegments(strings.ToLower(vb.Upstream))
+	for i := 0; i < max(len(sa), len(sb)); i++ {
+		ga, gb := "", ""
+		if i < len(sa) {
+			ga = sa[i]
+		}
+		if i < len(sb) {
+			gb = sb[i]
+		}
+		if ga == gb || rpmvercmp(ga, gb) == 0 && isAlpha(ga) == isAlpha(gb) {
+			continue
+		}
+		// rc, alpha, beta, git ...
+		if isAlpha(ga) || isAlpha(gb) {
+			return ChangePrerelease
+		}
+		switch i {
+		case 0:
+			return ChangeMajor
+		case 1:
+			return ChangeMinor
+		default:
+			return ChangePatch
+		}
+	}
+	// only separators are different
+	return ChangePatch
+}
+
+// ClassifyPackages as ClassifyChange, also detects a rebuild with the same version
+func ClassifyPackages(a, b *Package) string {
+	if a.VERSION == b.VERSION {
+		if !a.BUILDDATE.Equal(b.BUILDDATE) {
+			return ChangeRebuild
+		}
+		return ChangeNone
+	}
+	return ClassifyChange(a.VERSION, b.VERSION)
+}
+
+// VersionDistance estimates how many releases b is ahead of a:
+// difference of the first different numeric segment, 1 for other changes
+// "6.12.19" -> "6.12.23": 4
+func VersionDistance(a, b string) int {
+	if AlpmPkgVerCmp(a, b) >= 0 {
+		return 0
+	}
+	va, vb := ParseVersion(a), ParseVersion(b)
+	if va.Epoch != vb.Epoch || va.Upstream == vb.Upstream {
+		return 1
+	}
+	sa, sb := versionSegments(strings.ToLower(va.Upstream)), versionSegments(strings.ToLower(vb.Upstream))
+	for i := 0; i < min(len(sa), len(sb)); i++ {
+		if sa[i] == sb[i] {
+			continue
+		}
+		na, erra := strconv.Atoi(sa[i])
+		nb, errb := strconv.Atoi(sb[i])
+		if erra != nil || errb != nil || nb <= na {
+			return 1
+		}
+		return nb - na
+	}
+	return 1
+}
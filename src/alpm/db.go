@@ -10,6 +10,7 @@ import (
 	"mbc/tr"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,11 +28,41 @@ type (
 		PACKAGER  string
 		URL       string
 		DESC      string
+		PROVIDES  []string
+		CONFLICTS []string
+		REPLACES  []string
+		DEPENDS   []string
+		CSIZE     int64
+		ISIZE     int64
+		// local database
+		REASON      int
+		INSTALLDATE time.Time
+		SIZE        int64
+		VALIDATION  []string
+		FILES       []string
+		// only in long mode
+		FILENAME     string
+		BASE         string
+		ARCH         string
+		LICENSE      []string
+		GROUPS       []string
+		OPTDEPENDS   []string
+		MAKEDEPENDS  []string
+		CHECKDEPENDS []string
+		MD5SUM       string
+		SHA256SUM    string
+		PGPSIG       bool
 	}
 
 	Packages map[string]*Package
 )
 
+// KernelPattern: names of Manjaro kernels, as linux612 or linux612-rt
+const KernelPattern = `^linux\d{2,3}(-rt)?$`
+
+// KernelRegex matches a kernel name, the submatch is "-rt" or ""
+var KernelRegex = regexp.MustCompile(KernelPattern)
+
 // parse desc file content
 func (p *Package) set(descReader io.Reader, long bool) bool {
 	scanner := bufio.NewScanner(descReader)
@@ -70,9 +101,30 @@ func (p *Package) set(descReader io.Reader, long bool) bool {
 	p.NAME = getFieldString(adesc, "NAME")
 	p.BUILDDATE = getFieldDate(adesc, "BUILDDATE")
 	p.PACKAGER = getFieldString(adesc, "PACKAGER")
+	p.PROVIDES = getFieldStrings(adesc, "PROVIDES")
+	p.CONFLICTS = getFieldStrings(adesc, "CONFLICTS")
+	p.REPLACES = getFieldStrings(adesc, "REPLACES")
+	p.DEPENDS = getFieldStrings(adesc, "DEPENDS")
+	p.CSIZE = getFieldSize(adesc, "CSIZE")
+	p.ISIZE = getFieldSize(adesc, "ISIZE")
+	p.REASON = max(getFieldInt(adesc, "REASON"), ReasonExplicit)
+	p.INSTALLDATE = getFieldDate(adesc, "INSTALLDATE")
+	p.SIZE = getFieldSize(adesc, "SIZE")
+	p.VALIDATION = getFieldStrings(adesc, "VALIDATION")
 	if long {
 		p.DESC = getFieldString(adesc, "DESC")
 		p.URL = getFieldString(adesc, "URL")
+		p.FILENAME = getFieldString(adesc, "FILENAME")
+		p.BASE = getFieldString(adesc, "BASE")
+		p.ARCH = getFieldString(adesc, "ARCH")
+		p.LICENSE = getFieldStrings(adesc, "LICENSE")
+		p.GROUPS = getFieldStrings(adesc, "GROUPS")
+		p.OPTDEPENDS = getFieldStrings(adesc, "OPTDEPENDS")
+		p.MAKEDEPENDS = getFieldStrings(adesc, "MAKEDEPENDS")
+		p.CHECKDEPENDS = getFieldStrings(adesc, "CHECKDEPENDS")
+		p.MD5SUM = getFieldString(adesc, "MD5SUM")
+		p.SHA256SUM = getFieldString(adesc, "SHA256SUM")
+		p.PGPSIG = getFieldString(adesc, "PGPSIG") != ""
 	}
 	return true
 }
@@ -97,6 +149,14 @@ func getFieldString(adesc tdesc, key string) string {
 	return strings.TrimSpace(values[0])
 }
 
+func getFieldStrings(adesc tdesc, key string) []string {
+	values, ok := adesc[key]
+	if !ok || len(values) < 1 {
+		return nil
+	}
+	return values
+}
+
 func getFieldInt(adesc tdesc, key string) int {
 	if items, ok := adesc[key]; ok && len(items) > 0 {
 		if i, err := strconv.Atoi(items[0]); err == nil {
@@ -106,6 +166,15 @@ func getFieldInt(adesc tdesc, key string) int {
 	return -1
 }
 
+func getFieldSize(adesc tdesc, key string) int64 {
+	if items, ok := adesc[key]; ok && len(items) > 0 {
+		if i, err := strconv.ParseInt(items[0], 10, 64); err == nil {
+			return i
+		}
+	}
+	return 0
+}
+
 func getFieldDate(adesc tdesc, key string) time.Time {
 	if timestamp := int64(getFieldInt(adesc, key)); timestamp > -1 {
 		return time.Unix(timestamp, 0)
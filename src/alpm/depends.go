@@ -0,0 +1,76 @@
+package alpm
+
+import (
+	"strings"
+)
+
+// dependency modifiers, longest first
+var depMods = []string{">=", "<=", "=", ">", "<"}
+
+// DepName returns the package name of a dependency as "name>=1.2-3"
+func DepName(dep string) string {
+	if i := strings.IndexAny(dep, "<>=:"); i > -1 {
+		return dep[:i]
+	}
+	return dep
+}
+
+// ParseDep splits a dependency "name>=1.2-3" in name, modifier and version
+// modifier and version are empty for any version
+func ParseDep(dep string) (name, mod, version string) {
+	// optional dependency: "name: description"
+	dep, _, _ = strings.Cut(dep, ": ")
+	i := strings.IndexAny(dep, "<>=")
+	if i < 0 {
+		return dep, "", ""
+	}
+	name = dep[:i]
+	for _, m := range depMods {
+		if strings.HasPrefix(dep[i:], m) {
+			return name, m, dep[i+len(m):]
+		}
+	}
+	return name, "", ""
+}
+
+// Satisfies returns true if version matches the constraint of dependency "name>=1.2-3"
+// as libalpm: without pkgrel in one version, pkgrel is ignored
+func Satisfies(version, dep string) bool {
+	_, mod, depVersion := ParseDep(dep)
+	if mod == "" {
+		return true
+	}
+	cmp := AlpmPkgVerCmp(version, depVersion)
+	switch mod {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return false
+}
+
+// SatisfiedBy returns true if the package or one of its provides matches dependency
+func SatisfiedBy(pkg *Package, dep string) bool {
+	name, mod, _ := ParseDep(dep)
+	if pkg.NAME == name && Satisfies(pkg.VERSION, dep) {
+		return true
+	}
+	for _, provide := range pkg.PROVIDES {
+		pname, _, pversion := ParseDep(provide)
+		if pname != name {
+			continue
+		}
+		// as libalpm, a provide without version only satisfies a dependency without version
+		if mod == "" || (pversion != "" && Satisfies(pversion, dep)) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,39 @@
 package alpm
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 )
 
-var LOCALPATH = "/var/lib/pacman/local"
+var (
+	ROOTPATH  = "/"
+	DBPATH    = "/var/lib/pacman"
+	LOCALPATH = "/var/lib/pacman/local"
+)
+
+const (
+	ReasonExplicit   = 0
+	ReasonDependency = 1
+)
+
+// SetPaths as pacman --root and --dbpath, dbpath is relative to root if not set
+func SetPaths(root, dbpath string) {
+	if root != "" {
+		ROOTPATH = root
+		if dbpath == "" {
+			dbpath = filepath.Join(root, "var", "lib", "pacman")
+		}
+	}
+	if dbpath != "" {
+		DBPATH = dbpath
+	}
+	LOCALPATH = filepath.Join(DBPATH, "local")
+}
 
 func LocalDBExists() bool {
 	if _, err := os.Stat(LOCALPATH); err != nil {
@@ -21,27 +48,40 @@ func LoadLocal() (pkgs Packages, err error) {
 		return nil, fmt.Errorf("pacman DB not exists")
 	}
 
-	matches, _ := filepath.Glob("/var/lib/pacman/local/*/desc")
-	count := len(matches)
-	pkgs = make(map[string]*Package, count)
-	results := make(chan Package, count)
-	for _, match := range matches {
+	matches, _ := filepath.Glob(filepath.Join(LOCALPATH, "*", "desc"))
+	pkgs = make(Packages, len(matches))
+	jobs := make(chan string)
+	results := make(chan Package)
+	var wg sync.WaitGroup
 
-		go func(descFile string) {
-			f, err := os.Open(descFile)
-			if err != nil {
-				return
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for descFile := range jobs {
+				f, err := os.Open(descFile)
+				if err != nil {
+					continue
+				}
+				pkg := Package{REPO: "local"}
+				if pkg.set(f, true) && pkg.NAME != "" {
+					results <- pkg
+				}
+				f.Close()
 			}
-			defer f.Close()
-			pkg := Package{REPO: "local"}
-			if pkg.set(f, false) {
-				results <- pkg
-			}
-		}(match)
+		}()
 	}
 
-	for range count {
-		pkg := <-results
+	go func() {
+		for _, match := range matches {
+			jobs <- match
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for pkg := range results {
 		pkgs[pkg.NAME] = &pkg
 	}
 
@@ -51,6 +91,31 @@ func LoadLocal() (pkgs Packages, err error) {
 	return pkgs, nil
 }
 
+// LoadFiles reads the files list of an installed package
+func (p *Package) LoadFiles() error {
+	f, err := os.Open(filepath.Join(LOCALPATH, p.NAME+"-"+p.VERSION, "files"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p.FILES = p.FILES[:0]
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			section = ""
+		case strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%"):
+			section = line
+		case section == "%FILES%":
+			p.FILES = append(p.FILES, line)
+		}
+	}
+	return scanner.Err()
+}
+
 func FilterOnly(alls, wants Packages) (pkgs Packages) {
 
 	pkgs = make(Packages)
@@ -61,3 +126,70 @@ func FilterOnly(alls, wants Packages) (pkgs Packages) {
 	}
 	return pkgs
 }
+
+// Explicit: packages explicitly installed (pacman -Qe)
+func (pkgs Packages) Explicit() Packages {
+	result := make(Packages)
+	for name, pkg := range pkgs {
+		if pkg.REASON == ReasonExplicit {
+			result[name] = pkg
+		}
+	}
+	return result
+}
+
+// Dependencies: packages installed as dependencies (pacman -Qd)
+func (pkgs Packages) Dependencies() Packages {
+	result := make(Packages)
+	for name, pkg := range pkgs {
+		if pkg.REASON == ReasonDependency {
+			result[name] = pkg
+		}
+	}
+	return result
+}
+
+// Orphans: dependencies not required by an other package (pacman -Qdt)
+func (pkgs Packages) Orphans() Packages {
+	required := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, dep := range pkg.DEPENDS {
+			required[DepName(dep)] = true
+		}
+	}
+	result := make(Packages)
+	for name, pkg := range pkgs.Dependencies() {
+		if required[name] {
+			continue
+		}
+		provided := false
+		for _, provide := range pkg.PROVIDES {
+			if required[DepName(provide)] {
+				provided = true
+				break
+			}
+		}
+		if !provided {
+			result[name] = pkg
+		}
+	}
+	return result
+}
+
+// Foreign: packages not found in sync databases (pacman -Qm)
+func (pkgs Packages) Foreign(syncs ...Packages) Packages {
+	result := make(Packages)
+	for name, pkg := range pkgs {
+		found := false
+		for _, sync := range syncs {
+			if _, ok := sync[name]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result[name] = pkg
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package alpm
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	MIRRORSCONF = "/etc/pacman-mirrors.conf"
+	MIRRORLIST  = "/etc/pacman.d/mirrorlist"
+)
+
+// DetectBranch reads the Manjaro branch used by this system in pacman-mirrors.conf
+func DetectBranch() (string, error) {
+	f, err := os.Open(MIRRORSCONF)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// commented by default in pacman-mirrors.conf
+	branch := "stable"
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || strings.TrimSpace(key) != "Branch" {
+			continue
+		}
+		if value = strings.Trim(strings.TrimSpace(value), `"'`); value != "" {
+			branch = value
+		}
+	}
+	return branch, scanner.Err()
+}
+
+// DetectMirror reads the first server in mirrorlist,
+// as "https://mirror/manjaro/stable/$repo/$arch": mirror "https://mirror/manjaro/" and branch "stable"
+func DetectMirror(branches []string) (mirror, branch string, err error) {
+	f, err := os.Open(MIRRORLIST)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || strings.TrimSpace(key) != "Server" {
+			continue
+		}
+		server := strings.TrimSpace(value)
+		mirror, _, _ = strings.Cut(server, "$")
+		parts := strings.Split(strings.TrimSuffix(mirror, "/"), "/")
+		if len(parts) > 0 && slices.Contains(branches, parts[len(parts)-1]) {
+			branch = parts[len(parts)-1]
+			mirror = strings.Join(parts[:len(parts)-1], "/") + "/"
+		}
+		return mirror, branch, nil
+	}
+	return "", "", scanner.Err()
+}
@@ -0,0 +1,135 @@
+package alpm
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type vercmpTest struct {
+	a, b     string
+	expected int
+}
+
+// readVercmpTests reads testdata/vercmp.txt: "version1 version2 expected" by line
+func readVercmpTests(t *testing.T, filename string) []vercmpTest {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []vercmpTest{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			t.Fatalf("bad line in %s: %q", filename, line)
+		}
+		expected, err := strconv.Atoi(fields[2])
+		if err != nil {
+			t.Fatalf("bad line in %s: %q", filename, line)
+		}
+		tests = append(tests, vercmpTest{fields[0], fields[1], expected})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return tests
+}
+
+func TestAlpmPkgVerCmp(t *testing.T) {
+	for _, test := range readVercmpTests(t, "testdata/vercmp.txt") {
+		if got := AlpmPkgVerCmp(test.a, test.b); got != test.expected {
+			t.Errorf("AlpmPkgVerCmp(%q, %q) = %d, want %d", test.a, test.b, got, test.expected)
+		}
+		// as vercmptest.sh, reversed
+		if got := AlpmPkgVerCmp(test.b, test.a); got != -test.expected {
+			t.Errorf("AlpmPkgVerCmp(%q, %q) = %d, want %d", test.b, test.a, got, -test.expected)
+		}
+	}
+}
+
+func TestAlpmPkgVerCmpEmpty(t *testing.T) {
+	tests := []vercmpTest{
+		{"", "", 0},
+		{"1.0", "", 1},
+		{"", "1.0", -1},
+	}
+	for _, test := range tests {
+		if got := AlpmPkgVerCmp(test.a, test.b); got != test.expected {
+			t.Errorf("AlpmPkgVerCmp(%q, %q) = %d, want %d", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version  string
+		dep      string
+		expected bool
+	}{
+		{"1.0-1", "foo", true},
+		{"1.0-1", "foo: optional", true},
+
+		{"1.0-1", "foo<1.1", true},
+		{"1.1-1", "foo<1.1", false},
+		{"1.1-1", "foo<1.1-2", true},
+		{"1.1-2", "foo<1.1-2", false},
+		{"1.0-1", "foo<=1.0", true},
+		{"1.0-9", "foo<=1.0", true},
+		{"1.0-2", "foo<=1.0-1", false},
+		{"1.0", "foo<=1.0-1", true},
+
+		{"1.0-1", "foo=1.0", true},
+		{"1.0-5", "foo=1.0", true},
+		{"1.0-1", "foo=1.0-1", true},
+		{"1.0-2", "foo=1.0-1", false},
+		{"1.0", "foo=1.0-1", true},
+		{"1:1.0-1", "foo=1.0-1", false},
+		{"1:1.0-1", "foo=1:1.0", true},
+
+		{"1.0-1", "foo>=1.0", true},
+		{"0.9-9", "foo>=1.0", false},
+		{"1.0-1", "foo>=1.0-2", false},
+		{"1.0", "foo>=1.0-2", true},
+		{"1.1-1", "foo>1.0", true},
+		{"1.0-2", "foo>1.0", false},
+		{"1.0-2", "foo>1.0-1", true},
+		{"1.0", "foo>1.0-1", false},
+		{"1.0rc-1", "foo>1.0", false},
+	}
+	for _, test := range tests {
+		if got := Satisfies(test.version, test.dep); got != test.expected {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", test.version, test.dep, got, test.expected)
+		}
+	}
+}
+
+func TestParseDep(t *testing.T) {
+	tests := []struct {
+		dep                string
+		name, mod, version string
+	}{
+		{"foo", "foo", "", ""},
+		{"foo>=1.0-1", "foo", ">=", "1.0-1"},
+		{"foo<=1.0", "foo", "<=", "1.0"},
+		{"foo=1:1.0", "foo", "=", "1:1.0"},
+		{"foo>1", "foo", ">", "1"},
+		{"foo<1", "foo", "<", "1"},
+		{"foo: for bar", "foo", "", ""},
+	}
+	for _, test := range tests {
+		name, mod, version := ParseDep(test.dep)
+		if name != test.name || mod != test.mod || version != test.version {
+			t.Errorf("ParseDep(%q) = %q %q %q, want %q %q %q", test.dep, name, mod, version, test.name, test.mod, test.version)
+		}
+	}
+}
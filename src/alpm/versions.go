@@ -22,27 +22,29 @@ import (
 // @param evr		[epoch:]version[-release] string
 // @retval ep		pointer to epoch
 // @retval vp		pointer to version
-// @retval rp		pointer to release
-func parseEVR(evr string) (epoch, version, release string) {
-	parts := strings.SplitN(evr, ":", 2)
-	if len(parts) == 2 {
-		epoch = parts[0]
-		versionRelease := parts[1]
-		releaseParts := strings.SplitN(versionRelease, "-", 2)
-		version = releaseParts[0]
-		if len(releaseParts) == 2 {
-			release = releaseParts[1]
-		}
+// @retval rp		pointer to release, hasRelease is false for a NULL release
+func parseEVR(evr string) (epoch, version, release string, hasRelease bool) {
+	// s points to epoch terminator
+	s := 0
+	for s < len(evr) && evr[s] >= '0' && evr[s] <= '9' {
+		s++
+	}
+	if s < len(evr) && evr[s] == ':' {
+		epoch = evr[:s]
+		version = evr[s+1:]
 		if epoch == "" {
 			epoch = "0"
 		}
 	} else {
+		// different from RPM- always assume 0 epoch
 		epoch = "0"
-		releaseParts := strings.SplitN(parts[0], "-", 2)
-		version = releaseParts[0]
-		if len(releaseParts) == 2 {
-			release = releaseParts[1]
-		}
+		version = evr
+	}
+	// se points to version terminator
+	if se := strings.LastIndex(version, "-"); se > -1 {
+		release = version[se+1:]
+		version = version[:se]
+		hasRelease = true
 	}
 	return
 }
@@ -219,18 +221,15 @@ func AlpmPkgVerCmp(a, b string) int {
 	// Parse both versions into [epoch:]version[-release] triplets. We probably
 	// don't need epoch and release to support all the same magic, but it is
 	// easier to just run it all through the same code.
-	epoch1, ver1, rel1 := parseEVR(a)
-	epoch2, ver2, rel2 := parseEVR(b)
+	epoch1, ver1, rel1, hasRel1 := parseEVR(a)
+	epoch2, ver2, rel2, hasRel2 := parseEVR(b)
 
 	ret := rpmvercmp(epoch1, epoch2)
 	if ret == 0 {
 		ret = rpmvercmp(ver1, ver2)
-		if ret == 0 && rel1 != "" && rel2 != "" {
+		// release is ignored if one is missing
+		if ret == 0 && hasRel1 && hasRel2 {
 			ret = rpmvercmp(rel1, rel2)
-		} else if ret == 0 && rel1 != "" && rel2 == "" {
-			ret = 1
-		} else if ret == 0 && rel1 == "" && rel2 != "" {
-			ret = -1
 		}
 	}
 
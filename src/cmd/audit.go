@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var FlagAuditBranch branchNaneFlagType
+
+type auditResult struct {
+	name    string
+	local   string
+	version string // in branch
+	from    string // branch with the installed version
+}
+
+type auditRepo struct {
+	newers   []auditResult
+	olders   []auditResult
+	outsides []auditResult // not in branch, but in another
+}
+
+// audit installed packages against a branch, results by repository
+func audit(locals alpm.Packages, branch string, all map[string]alpm.Packages, order []string) (repos map[string]*auditRepo, foreigns []auditResult) {
+	repos = make(map[string]*auditRepo)
+	getRepo := func(name string) *auditRepo {
+		if _, ok := repos[name]; !ok {
+			repos[name] = &auditRepo{}
+		}
+		return repos[name]
+	}
+	// branch with this version of package
+	from := func(name, version string) string {
+		for _, b := range order {
+			if pkg, ok := all[b][name]; ok && pkg.VERSION == version {
+				return b
+			}
+		}
+		return ""
+	}
+
+	for name, local := range locals {
+		if pkg, ok := all[branch][name]; ok {
+			switch alpm.AlpmPkgVerCmp(local.VERSION, pkg.VERSION) {
+			case 1:
+				repo := getRepo(pkg.REPO)
+				repo.newers = append(repo.newers, auditResult{name, local.VERSION, pkg.VERSION, from(name, local.VERSION)})
+			case -1:
+				repo := getRepo(pkg.REPO)
+				repo.olders = append(repo.olders, auditResult{name, local.VERSION, pkg.VERSION, from(name, local.VERSION)})
+			}
+			continue
+		}
+		found := false
+		for _, b := range order {
+			if pkg, ok := all[b][name]; ok {
+				repo := getRepo(pkg.REPO)
+				repo.outsides = append(repo.outsides, auditResult{name, local.VERSION, "", from(name, local.VERSION)})
+				found = true
+				break
+			}
+		}
+		if !found {
+			foreigns = append(foreigns, auditResult{name, local.VERSION, "", ""})
+		}
+	}
+
+	for _, repo := range repos {
+		for _, results := range [][]auditResult{repo.newers, repo.olders, repo.outsides} {
+			sort.Slice(results, func(i, j int) bool { return results[i].name < results[j].name })
+		}
+	}
+	sort.Slice(foreigns, func(i, j int) bool { return foreigns[i].name < foreigns[j].name })
+	return repos, foreigns
+}
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: tr.T("installed packages not in the system branch"),
+	Long: `Compare installed packages to the branch of this system
+(in /etc/pacman-mirrors.conf or first branch of configuration)
+
+Lists, by repository:
+  packages newer than the branch (partial upgrades from archlinux or testing)
+  packages older than the branch (pending upgrades)
+  packages not in the branch, but in another
+and foreign packages (aur, local builds) not in any repository
+ex:
+	audit
+	audit --branch testing
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		locals, err := alpm.LoadLocal()
+		if err != nil {
+			return err
+		}
+		branch := FlagAuditBranch.value
+		if branch == "" {
+			branch = systemBranch(conf.Branches)
+		}
+
+		// versions are searched in this order, branch first
+		order := []string{branch}
+		for _, b := range append(conf.Branches, "archlinux") {
+			if b != branch {
+				order = append(order, b)
+			}
+		}
+		all := make(map[string]alpm.Packages, len(order))
+		for _, b := range order {
+			all[b], _ = alpm.Load(filepath.Join(cacheDir, b, "sync"), conf.Repos, b, false)
+		}
+
+		repos, foreigns := audit(locals, branch, all, order)
+
+		col := 12
+		for name := range locals {
+			col = max(col, len(name)+1)
+		}
+		display := func(title string, results []auditResult) {
+			if len(results) < 1 {
+				return
+			}
+			fmt.Printf("  %s (%d)\n", title, len(results))
+			for _, r := range results {
+				from := ""
+				if r.from != "" {
+					from = theme.Theme(r.from) + r.from + theme.ColorNone
+				}
+				fmt.Printf("    %-"+strconv.Itoa(col)+"s %-20s %-20s %s\n", r.name, r.local, r.version, from)
+			}
+		}
+
+		fmt.Printf("# %-"+strconv.Itoa(col+2)+"s %-20s %s\n", tr.T("installed"), tr.T("local"), theme.Theme(branch)+branch+theme.ColorNone)
+		for _, name := range conf.Repos {
+			repo, ok := repos[name]
+			if !ok {
+				continue
+			}
+			fmt.Println(theme.ColorBold + name + theme.ColorNone)
+			display(tr.T("newer than branch"), repo.newers)
+			display(tr.T("older than branch"), repo.olders)
+			display(tr.T("not in branch"), repo.outsides)
+			fmt.Println()
+		}
+		if len(foreigns) > 0 {
+			fmt.Println(theme.ColorBold + tr.T("foreign") + theme.ColorNone)
+			display(tr.T("not in a repository"), foreigns)
+			fmt.Println()
+		}
+		fmt.Printf("# %s: %s (%d %s)\n", tr.T("branch"), theme.Theme(branch)+branch+theme.ColorNone, len(locals), tr.T("packages"))
+		return nil
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	FlagAuditBranch = branchNaneFlagType{
+		value:  "",
+		valids: configBranches(),
+	}
+	auditCmd.Flags().Var(&FlagAuditBranch, "branch", tr.T("compare to this `branch`"))
+}
@@ -78,80 +78,218 @@ var (
 	FlagBranches       = Branch{}
 	FlagDiffNew   bool
 	FlagDiffRm    bool
+	FlagNoExclude bool
+	FlagRepoMoves bool
 )
 
 type diffResult struct {
 	first  string
 	second string
+	link   string // renamed or replaced: why first is second
 }
 
-func diff(diffs *[]diffResult, config Config, cacheDir string, branches []string, long bool) (int, int, int, [2]alpm.Packages) {
-	excludes := []string{
-		"pacman-mirrorlist", "reflector$", "linux-", "linux$", "r8168-lts", "tp_smapi",
-		"vhba-module", "virtualbox-host-modules", "acpi_call",
-		"archlinux-xdg-menu", "archlinux-wallpaper", "archlinux-themes-slim",
-		"arch-release", "devtools$", "archinstall", "mirro-rs", "pkgstats", "xdm-archlinux", "grub-customizer",
-		"nvidia$", "nvidia-lts", "nvidia-open$", "nvidia-open-lts",
+func (d diffResult) key() string {
+	if d.first != "" {
+		return d.first
 	}
+	return d.second
+}
+
+// packages hidden by exclude rules
+type diffHidden map[string]int
 
+func diff(diffs *[]diffResult, config Config, cacheDir string, branches []string, long bool) (int, int, int, [2]alpm.Packages, diffHidden) {
 	var tmp [2]alpm.Packages
 	var pkgs [2][]string
+	hidden := make(diffHidden)
 
 	tmp[0], _ = alpm.Load(filepath.Join(cacheDir, branches[0], "sync"), config.Repos, branches[0], long)
 	tmp[1], _ = alpm.Load(filepath.Join(cacheDir, branches[1], "sync"), config.Repos, branches[1], long)
 
+	// only in branch, not in against
+	excluded := func(branch, against, name string) bool {
+		if FlagNoExclude {
+			return false
+		}
+		for _, rule := range config.Excludes {
+			if rule.Match(branch, against, name) {
+				hidden[rule.Name]++
+				return true
+			}
+		}
+		return false
+	}
+
 	for key := range tmp[0] {
 		if _, exists := tmp[1][key]; !exists {
+			if excluded(branches[0], branches[1], key) {
+				continue
+			}
 			pkgs[0] = append(pkgs[0], key)
 		}
 	}
-	sort.Strings(pkgs[0])
-	l0 := len(pkgs[0])
-	l1 := 0
-
 	for key := range tmp[1] {
 		if _, exists := tmp[0][key]; !exists {
-			if branches[1] == "archlinux" && startsWith(key, &excludes) {
+			if excluded(branches[1], branches[0], key) {
 				continue
 			}
-			pkgs[0] = append(pkgs[0], key+" *")
-			l1 += 1
+			pkgs[1] = append(pkgs[1], key)
 		}
 	}
 	sort.Strings(pkgs[0])
-	if !long {
-		tmp[0] = make(map[string]*alpm.Package)
-		tmp[1] = make(map[string]*alpm.Package)
-	}
+	sort.Strings(pkgs[1])
+
+	// branches are in FlagBranches.toSlice() order: the second is the newer one
+	renamed := pairRenamed(pkgs[0], pkgs[1], tmp[1])
+	l0 := len(pkgs[0]) - len(renamed)
+	l1 := len(pkgs[1]) - len(renamed)
 
 	max := 12
 	for _, name := range pkgs[0] {
-		if strings.HasSuffix(name, "*") {
-			*diffs = append(*diffs, diffResult{"", strings.TrimSuffix(name, " *")})
+		if r, ok := renamed[name]; ok {
+			*diffs = append(*diffs, r)
 		} else {
-			*diffs = append(*diffs, diffResult{name, ""})
-			if len(name) > max {
-				max = len(name)
+			*diffs = append(*diffs, diffResult{first: name})
+		}
+		if len(name) > max {
+			max = len(name)
+		}
+	}
+	seconds := make(map[string]bool, len(renamed))
+	for _, r := range renamed {
+		seconds[r.second] = true
+	}
+	for _, name := range pkgs[1] {
+		if !seconds[name] {
+			*diffs = append(*diffs, diffResult{second: name})
+		}
+	}
+	sort.SliceStable(*diffs, func(i, j int) bool {
+		return (*diffs)[i].key() < (*diffs)[j].key()
+	})
+
+	if !long && !FlagRepoMoves {
+		tmp[0] = make(map[string]*alpm.Package)
+		tmp[1] = make(map[string]*alpm.Package)
+	}
+	return max, l0, l1, tmp, hidden
+}
+
+// packages in the two branches but not in the same repository
+func repoMoves(pkgs [2]alpm.Packages) (moves []string) {
+	for name, pkg := range pkgs[0] {
+		if other, ok := pkgs[1][name]; ok && other.REPO != pkg.REPO {
+			moves = append(moves, name)
+		}
+	}
+	sort.Strings(moves)
+	return moves
+}
+
+// levenshtein distance between two package names
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
 			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
 		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// similarity between 0 and 1, "foo" and "foo-bin" are very close
+func nameSimilarity(a, b string) float64 {
+	if strings.HasPrefix(a, b+"-") || strings.HasPrefix(b, a+"-") {
+		return 0.9
+	}
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 0
 	}
-	return max, l0, l1, tmp
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+var digitsRegex = regexp.MustCompile(`\d+`)
+
+// versioned siblings, as linux61 and linux612 or python311-foo and python312-foo
+func onlyDigitsDiffer(a, b string) bool {
+	return digitsRegex.ReplaceAllString(a, "0") == digitsRegex.ReplaceAllString(b, "0")
 }
 
-func startsWith(input string, excludes *[]string) bool {
-	for _, pattern := range *excludes {
-		matched, err := regexp.MatchString("^"+pattern, input)
-		if err == nil && matched {
-			return true
+// pair removed and added packages by REPLACES, PROVIDES, CONFLICTS of the newer branch,
+// else by a similar name, but not a sibling of another version
+func pairRenamed(removed, added []string, newer alpm.Packages) map[string]diffResult {
+	const minSimilarity = 0.85
+
+	pairs := make(map[string]diffResult)
+	paired := make(map[string]bool)
+	isRemoved := make(map[string]bool, len(removed))
+	for _, name := range removed {
+		isRemoved[name] = true
+	}
+
+	for _, name := range added {
+		pkg := newer[name]
+		if pkg == nil {
+			continue
+		}
+		relations := []struct {
+			link  string
+			items []string
+		}{
+			{tr.T("replaces"), pkg.REPLACES},
+			{tr.T("provides"), pkg.PROVIDES},
+			{tr.T("conflicts"), pkg.CONFLICTS},
+		}
+	relationLoop:
+		for _, relation := range relations {
+			for _, item := range relation.items {
+				old := alpm.DepName(item)
+				if _, ok := pairs[old]; ok || !isRemoved[old] {
+					continue
+				}
+				pairs[old] = diffResult{old, name, relation.link}
+				paired[name] = true
+				break relationLoop
+			}
+		}
+	}
+
+	for _, name := range added {
+		if paired[name] {
+			continue
+		}
+		best, score := "", minSimilarity
+		for _, old := range removed {
+			if _, ok := pairs[old]; ok || onlyDigitsDiffer(old, name) {
+				continue
+			}
+			if s := nameSimilarity(old, name); s >= score {
+				best, score = old, s
+			}
+		}
+		if best != "" {
+			pairs[best] = diffResult{best, name, tr.T("similar name")}
+			paired[name] = true
 		}
 	}
-	return false
+	return pairs
 }
 
 // diffCmd represents the diff command
 var diffCmd = &cobra.Command{
 	Use:   "diff",
-	Short: "branch packages differences",
+	Short: tr.T("branch packages differences"),
 	Long: `Differentiate the branches, display the packages unique to the branch.
 Example, compare "stable" to "archlinux":
 diff  -sa
@@ -169,21 +307,50 @@ lib32-gamescope-plus                               /
 		cacheDir := ctx.Value(ctxCacheDir).(string)
 		branches = FlagBranches.toSlice()
 
-		if updateDateFromFile() >= AutoUpdate {
-			updateCmd.Run(cmd, []string{""})
-			fmt.Println()
-		}
+		autoUpdate(cmd, cacheDir)
 
 		long := FlagDiffNew || FlagDiffRm
 
 		var diffs []diffResult
-		max, l0, l1, pkgs := diff(&diffs, conf, cacheDir, branches, long)
+		max, l0, l1, pkgs, hidden := diff(&diffs, conf, cacheDir, branches, long)
 		fmt.Printf("%-"+strconv.Itoa(max+11)+"s / %s\n", theme.Theme(branches[0])+branches[0]+theme.Theme(""), theme.Theme(branches[1])+branches[1]+theme.Theme(""))
+		renamed := 0
 		for _, d := range diffs {
+			if d.link != "" {
+				renamed++
+				fmt.Printf("%-"+strconv.Itoa(max)+"s > %s  %s\n", d.first, d.second, theme.ColorGray+"("+d.link+")"+theme.ColorNone)
+				continue
+			}
 			fmt.Printf("%-"+strconv.Itoa(max)+"s / %s\n", d.first, d.second)
 		}
 		fmt.Println()
 		fmt.Printf("# %-"+strconv.Itoa(max-2)+"d / %d\n", l0, l1)
+		if renamed > 0 {
+			fmt.Printf("# %s: %d\n", tr.T("renamed/replaced"), renamed)
+		}
+		if len(hidden) > 0 {
+			total := 0
+			for _, count := range hidden {
+				total += count
+			}
+			fmt.Printf("# %s: %d\n", tr.T("hidden by exclude rules"), total)
+			for _, rule := range conf.Excludes {
+				if count, ok := hidden[rule.Name]; ok {
+					fmt.Printf("#   %-16s %4d  %s\n", rule.Name, count, theme.ColorGray+rule.Reason+theme.ColorNone)
+				}
+			}
+		}
+
+		if FlagRepoMoves {
+			moves := repoMoves(pkgs)
+			fmt.Println()
+			fmt.Printf("%s (%d)\n", tr.T("Repository moves"), len(moves))
+			for _, name := range moves {
+				fmt.Printf("%-"+strconv.Itoa(max)+"s   %s%-10s%s > %s%s%s\n", name,
+					theme.Theme(branches[0]), pkgs[0][name].REPO, theme.ColorNone,
+					theme.Theme(branches[1]), pkgs[1][name].REPO, theme.ColorNone)
+			}
+		}
 
 		if FlagDiffNew && l1 > 0 {
 			fmt.Println()
@@ -207,20 +374,15 @@ lib32-gamescope-plus                               /
 		}
 	},
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) > 0 {
-			return fmt.Errorf(tr.T("use only flags! %v too mutch"), args)
-		}
-		result := FlagBranches.count()
-		if result != 2 {
-			return fmt.Errorf(tr.T("invalid branches specified: not %d"), 2)
+		if err := onlyFlags(cmd, args); err != nil {
+			return err
 		}
-		return nil
+		return twoBranches()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(diffCmd)
-	diffCmd.Short = tr.T(diffCmd.Short)
 	diffCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
 	diffCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
 	diffCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
@@ -228,6 +390,8 @@ func init() {
 
 	diffCmd.Flags().BoolVarP(&FlagDiffNew, "new", "", FlagDiffNew, tr.T("new packages detail"))
 	diffCmd.Flags().BoolVarP(&FlagDiffRm, "rm", "", FlagDiffRm, tr.T("removed manjaro packages detail"))
+	diffCmd.Flags().BoolVarP(&FlagNoExclude, "no-exclude", "", FlagNoExclude, tr.T("do not apply exclude rules"))
+	diffCmd.Flags().BoolVarP(&FlagRepoMoves, "repo-moves", "", FlagRepoMoves, tr.T("packages moved to another repository"))
 	diffCmd.MarkFlagsMutuallyExclusive("new", "rm")
 	diffCmd.MarkFlagsMutuallyExclusive("archlinux", "rm") // or display manjaro exclusive packages but not deleted
 }
@@ -24,10 +24,12 @@ type branchNaneFlagType struct {
 }
 
 var (
-	FlagAI         bool
-	FlagInstalled  bool
-	FlagAll        bool
-	FlagDetailInfo branchNaneFlagType
+	FlagAI           bool
+	FlagAIRefresh    bool
+	FlagAIPromptFile string
+	FlagInstalled    bool
+	FlagAll          bool
+	FlagDetailInfo   branchNaneFlagType
 )
 
 func (e *branchNaneFlagType) String() string {
@@ -61,15 +63,74 @@ func (e *branchNaneFlagType) Type() string {
 	return "branch"
 }
 
-func isInstalled(pkg string, test bool) string {
-	if !test {
-		return ""
+// branches of configuration file and archlinux, for flags
+func configBranches() []string {
+	valids := []string{"stable", "testing", "unstable"}
+	if conf, err := loadConfig(Config{}.configFile()); err == nil {
+		valids = conf.Branches
 	}
-	matches, _ := filepath.Glob("/var/lib/pacman/local/" + pkg + "-*/desc")
-	if len(matches) > 0 {
-		return "*"
+	return append(valids, "archlinux")
+}
+
+// systemBranch: branch in mirrorlist, in pacman-mirrors.conf or first branch of configuration
+func systemBranch(branches []string) string {
+	if _, branch, err := alpm.DetectMirror(branches); err == nil && branch != "" {
+		return branch
+	}
+	if branch, err := alpm.DetectBranch(); err == nil && slices.Contains(branches, branch) {
+		return branch
+	}
+	return branches[0]
+}
+
+// add the system branch if one branch is missing in flags,
+// or the next branch if the system branch is given: `diff -s` on a stable system is stable/testing
+// branches stay in chain order (stable ... archlinux), the system branch is first only if older
+func addSystemBranch(want int) {
+	if FlagBranches.count() == want-1 {
+		branches := configBranches()
+		FlagBranches.Set(nextBranch(branches, systemBranch(branches), FlagBranches.toSlice()))
+	}
+}
+
+// nextBranch: branch, or the next one in branches not already given
+func nextBranch(branches []string, branch string, given []string) string {
+	i := max(slices.Index(branches, branch), 0)
+	for range branches {
+		if !slices.Contains(given, branches[i]) {
+			return branches[i]
+		}
+		i = (i + 1) % len(branches)
 	}
-	return ""
+	return branch
+}
+
+// localDetails of an installed package: date, reason, size, files and validation
+func localDetails(local *alpm.Package) string {
+	reason := tr.T("explicit")
+	if local.REASON == alpm.ReasonDependency {
+		reason = tr.T("dependency")
+	}
+	details := []string{local.INSTALLDATE.Format("06-01-02 15:04"), reason, humanSize(local.SIZE)}
+	if err := local.LoadFiles(); err == nil {
+		details = append(details, fmt.Sprintf("%d %s", len(local.FILES), tr.T("files")))
+	}
+	if len(local.VALIDATION) > 0 {
+		details = append(details, tr.T("validated by")+" "+strings.Join(local.VALIDATION, ", "))
+	}
+	return strings.Join(details, ", ")
+}
+
+// installed version compared to a branch version:
+// "<" local is behind, "=" same version, ">" local is ahead
+func localMark(local, version string) string {
+	switch alpm.AlpmPkgVerCmp(local, version) {
+	case -1:
+		return theme.ColorUnstable + "<" + theme.ColorNone
+	case 1:
+		return theme.ColorArch + ">" + theme.ColorNone
+	}
+	return theme.ColorStable + "=" + theme.ColorNone
 }
 
 // cobra arg is regex or not ?
@@ -120,10 +181,48 @@ func getKeys(pkgs map[string]alpm.Packages, search string) (keys []string) {
 	return
 }
 
+// data of the package prompt template
+type packagePromptData struct {
+	Name     string
+	Repo     string
+	Lang     string
+	Desc     string
+	URL      string
+	Licenses []string
+	Depends  []string
+	Versions []struct{ Branch, Version string }
+}
+
+// packagePrompt: template of --ai-prompt-file, ai.prompts.package or default
+func packagePrompt(conf Config, pkgs map[string]alpm.Packages, branches []string, name string) (string, error) {
+	data := packagePromptData{Name: name, Lang: os.Getenv("LANG")}
+	for _, branch := range branches {
+		pkg, ok := pkgs[branch][name]
+		if !ok {
+			continue
+		}
+		if data.Repo == "" {
+			data.Repo, data.Desc, data.URL = pkg.REPO, pkg.DESC, pkg.URL
+			data.Licenses, data.Depends = pkg.LICENSE, pkg.DEPENDS
+		}
+		data.Versions = append(data.Versions, struct{ Branch, Version string }{branch, pkg.VERSION})
+	}
+
+	file := FlagAIPromptFile
+	if file == "" {
+		file = conf.API.Prompts["package"]
+	}
+	template, err := ai.LoadPrompt(expandHome(file), ai.PackagePrompt)
+	if err != nil {
+		return "", err
+	}
+	return ai.Render(template, data)
+}
+
 // infoCmd represents the info command
 var infoCmd = &cobra.Command{
 	Use:   "info pakageName(s)",
-	Short: "a brief description of your package",
+	Short: tr.T("a brief description of your package"),
 	Long: `Compare versions for one or more packages.
 Returns version differences across branches, if differences exist.
 
@@ -132,7 +231,9 @@ ex:
 	info 'linux\d{2}$' 'linux\d..$' '^linux\d.*-rt$'
 	info "#kernel"  	# search all kernels
 	info pacman --detail t		# run at end pacman -Si in branch Testing
+	info pacman -i			# installed version: "<" behind, "=" same, ">" ahead of branch
 	echo -e "pacman grub" | mbc info -
+	info pacman --ai --ai-prompt-file ~/prompt.tmpl	# Go template, with package data
 	`,
 	Args:       cobra.MinimumNArgs(1),
 	ArgAliases: []string{"package"},
@@ -141,10 +242,7 @@ ex:
 		conf := ctx.Value(ctxConfigVars).(Config)
 		cacheDir := ctx.Value(ctxCacheDir).(string)
 
-		if updateDateFromFile() >= AutoUpdate {
-			updateCmd.Run(cmd, []string{""})
-			fmt.Println()
-		}
+		autoUpdate(cmd, cacheDir)
 
 		branches := append(conf.Branches, "archlinux")
 
@@ -160,10 +258,18 @@ ex:
 			}
 		}
 
+		locals := alpm.Packages{}
+		if FlagInstalled {
+			var err error
+			if locals, err = alpm.LoadLocal(); err != nil {
+				fmt.Fprintln(os.Stderr, "WARNING!", err)
+			}
+		}
+
 		var warnings []string
 		pkgs := make(map[string]alpm.Packages, len(branches))
 		for _, branch := range branches {
-			p, warns := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+			p, warns := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, FlagAI)
 			pkgs[branch] = p
 			if warns != nil {
 				warnings = append(warnings, warns...)
@@ -177,12 +283,17 @@ ex:
 				os.Exit(2)
 			}
 			if len(pkgName) > 6 && pkgName[0:7] == "#kernel" {
-				pkgName = `^linux\d{2,3}(-rt)?$`
+				pkgName = alpm.KernelPattern
 			}
-			repo := ""
 
 			for _, pkgName = range getKeys(pkgs, pkgName) {
-				fmt.Printf("\n%s %s", pkgName, isInstalled(pkgName, FlagInstalled))
+				local := locals[pkgName]
+				if local != nil {
+					fmt.Printf("\n%s   %s", pkgName, theme.ColorGray+tr.T("installed")+": "+theme.ColorNone+local.VERSION)
+					fmt.Printf("\n   %s%s%s", theme.ColorGray, localDetails(local), theme.ColorNone)
+				} else {
+					fmt.Printf("\n%s ", pkgName)
+				}
 				oldVersion := ""
 				for _, branch := range branches {
 					//fmt.Println("  ", Theme(branch)+branch+Theme(""))
@@ -203,10 +314,12 @@ ex:
 						}
 						oldVersion = pkg.VERSION
 						fmt.Println()
-						fmt.Printf("   Version:  %-11s %s\n", padRightANSI(theme.Theme(branch)+branch+theme.Theme(""), 11), ver)
+						mark := ""
+						if local != nil {
+							mark = "  " + padRightANSI("", 20-realLength(ver)) + tr.T("local") + " " + localMark(local.VERSION, pkg.VERSION)
+						}
+						fmt.Printf("   Version:  %-11s %s%s\n", padRightANSI(theme.Theme(branch)+branch+theme.Theme(""), 11), ver, mark)
 						fmt.Printf("   Date:     %-11s %s\t%s\n", " ", pkg.BUILDDATE.Format("06-01-02 15:04"), days)
-
-						repo = pkg.REPO
 					} /*else {
 						fmt.Printf("\n   -         %s\n", Theme(branch)+branch+Theme(""))
 					}*/
@@ -215,17 +328,22 @@ ex:
 
 				if len(FlagDetailInfo.value) > 0 {
 					fmt.Println()
-					FlagBranches.Set(string(FlagDetailInfo.value))
-					FlagInfo = true
-					var args = []string{pkgName}
-					pacmanCmd.Run(cmd, args)
+					FlagInfo = 2 // as pacman -Sii
+					runPacman(conf, cacheDir, FlagDetailInfo.value, []string{pkgName})
 				}
 				if FlagAI {
 					if a := ai.MakeAi(conf.API); a != nil {
-						s := a.Ask(ai.GetAskPackage(pkgName, repo))
-						if s != "" {
-							fmt.Println()
-							fmt.Println(s)
+						cache := ai.Cache{Dir: filepath.Join(cacheDir, "ai"), TTL: conf.API.CacheTTL, Refresh: FlagAIRefresh}
+						prompt, err := packagePrompt(conf, pkgs, branches, pkgName)
+						if err == nil {
+							var s string
+							if s, err = ai.AskCached(a, cache, prompt, pkgName, oldVersion); err == nil && s != "" {
+								fmt.Println()
+								fmt.Println(s)
+							}
+						}
+						if err != nil {
+							fmt.Fprintln(os.Stderr, "WARNING!", err)
 						}
 					}
 				}
@@ -246,23 +364,19 @@ ex:
 func init() {
 
 	rootCmd.AddCommand(infoCmd)
-	infoCmd.Short = tr.T(infoCmd.Short)
 
-	if len(os.Getenv("GEMINI_API_KEY")) > 1 {
-		infoCmd.Flags().BoolVarP(&FlagAI, "ai", "", FlagAI, tr.T("add General Info by Gemini"))
-	}
-	if alpm.LocalDBExists() {
-		infoCmd.Flags().BoolVarP(&FlagInstalled, "installed", "i", FlagInstalled, tr.T("package installed ?"))
-		if _, err := os.Stat("/usr/bin/pacman"); err == nil {
-			infoCmd.Flags().Var(&FlagDetailInfo, "detail", tr.T("run pacman -Si in `branch`"))
-		}
+	if conf, err := loadConfig(Config{}.configFile()); err == nil && ai.MakeAi(conf.API) != nil {
+		infoCmd.Flags().BoolVarP(&FlagAI, "ai", "", FlagAI, tr.T("add General Info by AI"))
+		infoCmd.Flags().BoolVarP(&FlagAIRefresh, "ai-refresh", "", FlagAIRefresh, tr.T("ask again, do not use the cached response"))
+		infoCmd.Flags().StringVarP(&FlagAIPromptFile, "ai-prompt-file", "", "", tr.T("prompt template `file`"))
 	}
+	infoCmd.Flags().BoolVarP(&FlagInstalled, "installed", "i", FlagInstalled, tr.T("installed version"))
+	infoCmd.Flags().Var(&FlagDetailInfo, "detail", tr.T("run pacman -Si in `branch`"))
 	infoCmd.Flags().BoolVarP(&FlagAll, "all", "", FlagAll, tr.T("display all branches"))
 
-	conf, _ := loadConfig(Config{}.configFile())
 	FlagDetailInfo = branchNaneFlagType{
 		value:  "",
-		valids: append(conf.Branches, "archlinux"),
+		valids: configBranches(),
 	}
 
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"mbc/alpm"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// a host following branch, as pacman-mirrors writes the mirrorlist
+func setSystemBranch(t *testing.T, branch string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // default configuration
+	mirrorlist := filepath.Join(t.TempDir(), "mirrorlist")
+	os.WriteFile(mirrorlist, []byte("Server = https://mirror.example.org/"+branch+"/$repo/$arch\n"), 0o644)
+	oldList, oldConf := alpm.MIRRORLIST, alpm.MIRRORSCONF
+	alpm.MIRRORLIST, alpm.MIRRORSCONF = mirrorlist, filepath.Join(t.TempDir(), "none")
+	oldFlags := FlagBranches
+	t.Cleanup(func() {
+		alpm.MIRRORLIST, alpm.MIRRORSCONF = oldList, oldConf
+		FlagBranches = oldFlags
+	})
+}
+
+func TestAddSystemBranch(t *testing.T) {
+	tests := []struct {
+		system   string
+		flags    Branch
+		want     int
+		expected []string
+	}{
+		{"stable", Branch{}, 1, []string{"stable"}},
+		{"testing", Branch{}, 1, []string{"testing"}},
+		{"stable", Branch{FlagUnstable: true}, 2, []string{"stable", "unstable"}},
+		{"testing", Branch{FlagStable: true}, 2, []string{"stable", "testing"}},
+		// system branch given: the next one
+		{"stable", Branch{FlagStable: true}, 2, []string{"stable", "testing"}},
+		{"unstable", Branch{FlagUnstable: true}, 2, []string{"unstable", "archlinux"}},
+		{"stable", Branch{FlagArchlinux: true}, 2, []string{"stable", "archlinux"}},
+		// nothing missing
+		{"stable", Branch{FlagTesting: true, FlagUnstable: true}, 2, []string{"testing", "unstable"}},
+	}
+	for _, test := range tests {
+		setSystemBranch(t, test.system)
+		FlagBranches = test.flags
+		addSystemBranch(test.want)
+		if got := FlagBranches.toSlice(); !slices.Equal(got, test.expected) {
+			t.Errorf("%s system, %v: %v, want %v", test.system, test.flags.toSlice(), got, test.expected)
+		}
+	}
+}
+
+func TestNextBranch(t *testing.T) {
+	branches := []string{"stable", "testing", "unstable", "archlinux"}
+	if got := nextBranch(branches, "archlinux", []string{"archlinux"}); got != "stable" {
+		t.Errorf("after archlinux: %q, want \"stable\"", got)
+	}
+	if got := nextBranch(branches, "other", nil); got != "stable" {
+		t.Errorf("unknown branch: %q, want \"stable\"", got)
+	}
+}
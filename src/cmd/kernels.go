@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	KernelReleasesURL = "https://www.kernel.org/releases.json"
+	// kernel.org data is downloaded again after
+	KernelReleasesTTL = 24 * time.Hour
+)
+
+var FlagReleasesFile string
+
+// release of kernel.org, by family "612"
+type kernelRelease struct {
+	Version string
+	Moniker string // mainline, stable, longterm
+	EOL     bool
+}
+
+var familyRegex = regexp.MustCompile(`^(\d+)\.(\d+)`)
+
+// kernelFamily "6.12.20" or "6.14-rc7" to "612"
+func kernelFamily(version string) string {
+	if match := familyRegex.FindStringSubmatch(version); match != nil {
+		return match[1] + match[2]
+	}
+	return ""
+}
+
+// parseKernelReleases reads releases.json or the rss feed kdist.xml
+func parseKernelReleases(data []byte) (map[string]kernelRelease, error) {
+	releases := make(map[string]kernelRelease)
+	add := func(release kernelRelease) {
+		family := kernelFamily(release.Version)
+		if _, ok := releases[family]; family != "" && !ok {
+			releases[family] = release
+		}
+	}
+
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "<") {
+		var rss struct {
+			Channel struct {
+				Items []struct {
+					Title string `xml:"title"`
+				} `xml:"item"`
+			} `xml:"channel"`
+		}
+		if err := xml.Unmarshal(data, &rss); err != nil {
+			return nil, err
+		}
+		for _, item := range rss.Channel.Items {
+			// "6.12.20: longterm"
+			version, moniker, found := strings.Cut(item.Title, ": ")
+			if !found {
+				continue
+			}
+			add(kernelRelease{version, strings.Fields(moniker + " ")[0], strings.Contains(item.Title, "EOL")})
+		}
+	} else {
+		var feed struct {
+			Releases []struct {
+				Version string `json:"version"`
+				Moniker string `json:"moniker"`
+				IsEOL   bool   `json:"iseol"`
+			} `json:"releases"`
+		}
+		if err := json.Unmarshal(data, &feed); err != nil {
+			return nil, err
+		}
+		for _, r := range feed.Releases {
+			add(kernelRelease{r.Version, r.Moniker, r.IsEOL})
+		}
+	}
+	if len(releases) < 1 {
+		return nil, errors.New("kernel.org: " + tr.T("no release found"))
+	}
+	return releases, nil
+}
+
+// kernelReleases from --releases-file, or kernel.org cached in cacheDir
+// the cache is used if kernel.org is not reachable
+func kernelReleases(cacheDir string) (map[string]kernelRelease, error) {
+	if FlagReleasesFile != "" {
+		data, err := os.ReadFile(expandHome(FlagReleasesFile))
+		if err != nil {
+			return nil, err
+		}
+		return parseKernelReleases(data)
+	}
+
+	cacheFile := filepath.Join(cacheDir, "kernel.org", "releases.json")
+	if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < KernelReleasesTTL {
+		if data, err := os.ReadFile(cacheFile); err == nil {
+			return parseKernelReleases(data)
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(KernelReleasesURL)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("kernel.org: %s", resp.Status)
+		}
+	}
+	var data []byte
+	if err == nil {
+		data, err = io.ReadAll(resp.Body)
+	}
+	if err == nil {
+		var releases map[string]kernelRelease
+		if releases, err = parseKernelReleases(data); err == nil {
+			if err := writeFile(cacheFile, data); err != nil {
+				// not cached, kernel.org is asked again next time
+				fmt.Fprintln(os.Stderr, "WARNING!", err)
+			}
+			return releases, nil
+		}
+	}
+
+	// offline: old data is better than nothing
+	if data, cerr := os.ReadFile(cacheFile); cerr == nil {
+		return parseKernelReleases(data)
+	}
+	return nil, err
+}
+
+// kernelStatus: longterm, stable, mainline or EOL
+func kernelStatus(kernel string, releases map[string]kernelRelease) string {
+	family := strings.TrimSuffix(strings.TrimPrefix(kernel, "linux"), "-rt")
+	release, ok := releases[family]
+	switch {
+	case len(releases) < 1:
+		return "?"
+	case !ok || release.EOL:
+		return "EOL"
+	}
+	return release.Moniker
+}
+
+func kernelStatusColor(status string) string {
+	switch status {
+	case "EOL":
+		return theme.ColorUnstable
+	case "longterm":
+		return theme.ColorStable
+	case "mainline":
+		return theme.ColorTesting
+	}
+	return theme.ColorNone
+}
+
+// kernelModules: extramodules of a kernel, "nvidia" for linux612-nvidia
+func kernelModules(pkgs alpm.Packages, kernel string) (modules []string) {
+	for name := range pkgs {
+		if module, found := strings.CutPrefix(name, kernel+"-"); found && module != "headers" && module != "docs" {
+			// linux612-rt and its modules are another kernel
+			if _, rt := pkgs[kernel+"-rt"]; rt && (module == "rt" || strings.HasPrefix(module, "rt-")) {
+				continue
+			}
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// kernelsCmd represents the kernels command
+var kernelsCmd = &cobra.Command{
+	Use:   "kernels",
+	Short: tr.T("kernels over branches, with kernel.org status"),
+	Long: `All linuxXY and linuxXY-rt kernels in branches, with:
+  status by kernel.org: longterm, stable, mainline or EOL
+  version and build age in each branch
+  headers and extramodules (linuxXY-*) packages
+
+kernel.org data is cached one day in the databases directory
+--releases-file reads releases.json or kdist.xml offline
+ex:
+	kernels
+	kernels --releases-file ~/Downloads/releases.json
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		var releases map[string]kernelRelease
+		var releasesErr error
+		releasesChan := make(chan struct{})
+		go func() {
+			defer close(releasesChan)
+			releases, releasesErr = kernelReleases(cacheDir)
+		}()
+
+		pkgs := make(map[string]alpm.Packages, len(conf.Branches))
+		for _, branch := range conf.Branches {
+			pkgs[branch], _ = alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+		}
+		kernels := getKeys(pkgs, alpm.KernelPattern)
+		sortKernels(kernels)
+
+		<-releasesChan
+		if releasesErr != nil {
+			fmt.Fprintln(os.Stderr, "WARNING!", releasesErr)
+		}
+
+		for _, kernel := range kernels {
+			status := kernelStatus(kernel, releases)
+			upstream := ""
+			if release, ok := releases[strings.TrimSuffix(strings.TrimPrefix(kernel, "linux"), "-rt")]; ok {
+				upstream = theme.ColorGray + "kernel.org " + release.Version + theme.ColorNone
+			}
+			fmt.Printf("%s%-12s%s %s %s\n", theme.ColorBold, kernel, theme.ColorNone, padRightANSI(kernelStatusColor(status)+status+theme.ColorNone, 10), upstream)
+
+			for _, branch := range conf.Branches {
+				pkg, ok := pkgs[branch][kernel]
+				if !ok {
+					continue
+				}
+				headers := ""
+				if h, ok := pkgs[branch][kernel+"-headers"]; !ok {
+					headers = theme.ColorUnstable + tr.T("no headers") + theme.ColorNone
+				} else if h.VERSION != pkg.VERSION {
+					headers = theme.ColorUnstable + "headers " + h.VERSION + theme.ColorNone
+				}
+				modules := kernelModules(pkgs[branch], kernel)
+				fmt.Printf("  %s %-20s %5d %s  %s %s\n",
+					padRightANSI(theme.Theme(branch)+branch+theme.ColorNone, 10), pkg.VERSION,
+					ageDays(pkg.BUILDDATE), tr.T("days"), headers,
+					theme.ColorGray+strings.Join(modules, " ")+theme.ColorNone)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("# %d %s\n", len(kernels), tr.T("kernels"))
+		return nil
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(kernelsCmd)
+	kernelsCmd.Flags().StringVarP(&FlagReleasesFile, "releases-file", "", "", tr.T("kernel.org releases.json or kdist.xml `file`"))
+}
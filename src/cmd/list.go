@@ -11,33 +11,55 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
-var FlagPackager string = "manjaro"
+var (
+	FlagPackager     string = "manjaro"
+	FlagListSince    ageFlagType
+	FlagListPackages bool
+)
 
 type listResult struct {
-	name  string
-	count int
+	name     string
+	counts   []int                      // by branch
+	last     time.Time                  // last build, in all branches
+	packages map[string][]*alpm.Package // by name, by branch
 }
 
-func listP(packagers *[]listResult, config Config, cacheDir string, branch string) int {
+func listP(packagers *[]listResult, config Config, cacheDir string, branches []string, since time.Duration) int {
 
 	reg, err := regexp.Compile(FlagPackager)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR! bad regex: %s\n", FlagGrep)
+		fmt.Fprintf(os.Stderr, "ERROR! bad regex: %s\n", FlagPackager)
 		os.Exit(2)
 	}
 
-	fmt.Println(theme.Theme(branch) + branch + theme.Theme(""))
-	fmt.Println()
-
-	items := make(map[string]int)
-	pkgs, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), config.Repos, branch, false)
-	for _, pkg := range pkgs {
-		if reg.MatchString(pkg.PACKAGER) {
-			items[pkg.PACKAGER] += 1
+	items := make(map[string]*listResult)
+	for i, branch := range branches {
+		pkgs, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), config.Repos, branch, false)
+		for _, pkg := range pkgs {
+			if !reg.MatchString(pkg.PACKAGER) {
+				continue
+			}
+			if since > 0 && time.Since(pkg.BUILDDATE) > since {
+				continue
+			}
+			item, ok := items[pkg.PACKAGER]
+			if !ok {
+				item = &listResult{name: pkg.PACKAGER, counts: make([]int, len(branches)), packages: make(map[string][]*alpm.Package)}
+				items[pkg.PACKAGER] = item
+			}
+			item.counts[i] += 1
+			if pkg.BUILDDATE.After(item.last) {
+				item.last = pkg.BUILDDATE
+			}
+			if _, ok := item.packages[pkg.NAME]; !ok {
+				item.packages[pkg.NAME] = make([]*alpm.Package, len(branches))
+			}
+			item.packages[pkg.NAME][i] = pkg
 		}
 	}
 
@@ -49,7 +71,7 @@ func listP(packagers *[]listResult, config Config, cacheDir string, branch strin
 
 	max := 10
 	for _, key := range keys {
-		*packagers = append(*packagers, listResult{key, items[key]})
+		*packagers = append(*packagers, *items[key])
 		if len(key) > max {
 			max = len(key)
 		}
@@ -65,36 +87,93 @@ func grayEmail(s string) string {
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
-	Short: "list packagers",
-	Long:  ``,
+	Short: tr.T("list packagers"),
+	Long: `Packages by packager, a column by branch
+"last": days since the last build of the packager
+ex:
+	list -stu
+	list --grep . --since 30d
+	list --grep 'philm' --packages
+`,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
 		conf := ctx.Value(ctxConfigVars).(Config)
 		cacheDir := ctx.Value(ctxCacheDir).(string)
+		branches := FlagBranches.toSlice()
 
 		var packagers []listResult
-		max := listP(&packagers, conf, cacheDir, FlagBranches.toSlice()[0]) + 1
+		col := listP(&packagers, conf, cacheDir, branches, FlagListSince.value) + 1
 
+		fmt.Printf("# %-"+strconv.Itoa(col-2)+"s", tr.T("packager"))
+		for _, branch := range branches {
+			fmt.Printf(" %s", strings.Repeat(" ", max(10-len(branch), 0))+theme.Theme(branch)+branch+theme.Theme(""))
+		}
+		fmt.Printf(" %6s\n", tr.T("last"))
+
+		colVersion := 10
 		for _, packager := range packagers {
-			fmt.Printf("%-"+strconv.Itoa(max+10)+"s %5d\n", grayEmail(packager.name), packager.count)
+			for _, versions := range packager.packages {
+				for _, pkg := range versions {
+					if pkg != nil {
+						colVersion = max(colVersion, len(pkg.VERSION))
+					}
+				}
+			}
+		}
+		total := make([]int, len(branches))
+		for _, packager := range packagers {
+			fmt.Printf("%s", padRightANSI(grayEmail(packager.name), col))
+			for i, count := range packager.counts {
+				fmt.Printf(" %10d", count)
+				total[i] += count
+			}
+			fmt.Printf(" %6d\n", ageDays(packager.last))
+
+			if FlagListPackages {
+				names := make([]string, 0, len(packager.packages))
+				for name := range packager.packages {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Printf("    %-"+strconv.Itoa(col-4)+"s", name)
+					for i, pkg := range packager.packages[name] {
+						version := "-"
+						if pkg != nil {
+							version = pkg.VERSION
+						}
+						fmt.Printf(" %s", padRightANSI(theme.Theme(branches[i])+version+theme.ColorNone, colVersion))
+					}
+					fmt.Println()
+				}
+			}
+		}
+		fmt.Println()
+		fmt.Printf("# %-"+strconv.Itoa(col-2)+"s", strconv.Itoa(len(packagers))+" "+tr.T("packagers"))
+		for _, count := range total {
+			fmt.Printf(" %10d", count)
+		}
+		fmt.Println()
+		if FlagListSince.value > 0 {
+			fmt.Printf("# %s: %s\n", tr.T("built since"), FlagListSince.String())
 		}
 	},
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) > 0 {
-			return fmt.Errorf("use only flags! %v too mutch", args)
+		if err := onlyFlags(cmd, args); err != nil {
+			return err
 		}
+		addSystemBranch(1)
 		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
-	listCmd.Short = tr.T(listCmd.Short)
 	listCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
 	listCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
 	listCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
 	listCmd.Flags().BoolVarP(&FlagBranches.FlagArchlinux, "archlinux", "a", FlagBranches.FlagArchlinux, "archlinux "+tr.T("branch"))
-	listCmd.MarkFlagsOneRequired("stable", "testing", "unstable", "archlinux")
-	listCmd.MarkFlagsMutuallyExclusive("stable", "testing", "unstable", "archlinux")
 	listCmd.Flags().StringVarP(&FlagPackager, "grep", "", FlagPackager, tr.T("packager filter (regex)"))
+	listCmd.Flags().Var(&FlagListSince, "since", tr.T("only packages built since, ex: 30d, 8w"))
+	listCmd.Flags().BoolVarP(&FlagListPackages, "packages", "", FlagListPackages, tr.T("list packages of each packager"))
 }
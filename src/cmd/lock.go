@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/tr"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// cacheLock is an advisory lock (flock) on the cache directory
+// update takes it exclusive, readers take it shared
+type cacheLock struct {
+	file    *os.File
+	timeout time.Duration
+}
+
+// lock held by this process, an auto-update converts it
+var cacheLocker *cacheLock
+
+func lockCache(cacheDir string, exclusive bool, timeout time.Duration) (*cacheLock, error) {
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(cacheDir, ".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &cacheLock{file: f, timeout: timeout}
+	if err := l.lock(exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// lock waits for the lock or returns an error after timeout
+func (l *cacheLock) lock(exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	deadline := time.Now().Add(l.timeout)
+	for {
+		err := syscall.Flock(int(l.file.Fd()), how|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if err != syscall.EWOULDBLOCK {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s %s (%v)", tr.T("cache is locked by another process"), l.file.Name(), l.timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// relock converts the lock, flock does not convert atomically:
+// two readers upgrading at once would wait for each other, so the lock is released first
+func (l *cacheLock) relock(exclusive bool) error {
+	if l == nil {
+		return nil
+	}
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	return l.lock(exclusive)
+}
+
+func (l *cacheLock) unlock() {
+	if l == nil {
+		return
+	}
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+}
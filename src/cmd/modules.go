@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var FlagModulesAll bool
+
+// problem of an extramodule package, translated
+var (
+	moduleMismatch    = tr.T("built against other kernel")
+	moduleUnversioned = tr.T("no kernel version in depends")
+	moduleUnpinned    = tr.T("kernel version not pinned with =")
+)
+
+type moduleResult struct {
+	name    string // package name: linux612-nvidia
+	version string
+	depend  string // "linux612=6.12.20-1" from DEPENDS
+	problem string
+}
+
+type kernelModulesResult struct {
+	kernel  *alpm.Package
+	modules []moduleResult
+	missing []string // modules of other kernels
+}
+
+// kernelDepend: the dependency of a module on its kernel, "" if not found
+func kernelDepend(pkg *alpm.Package, kernel string) string {
+	for _, dep := range pkg.DEPENDS {
+		if alpm.DepName(dep) == kernel {
+			return dep
+		}
+	}
+	return ""
+}
+
+// checkModules of each kernel of a branch
+func checkModules(pkgs alpm.Packages) []kernelModulesResult {
+	kernels := make([]string, 0, 10)
+	for name := range pkgs {
+		if alpm.KernelRegex.MatchString(name) {
+			kernels = append(kernels, name)
+		}
+	}
+	sortKernels(kernels)
+
+	// all modules by flavor, "" or "-rt"
+	all := map[string][]string{}
+	byKernel := make(map[string][]string, len(kernels))
+	for _, kernel := range kernels {
+		flavor := alpm.KernelRegex.FindStringSubmatch(kernel)[1]
+		byKernel[kernel] = kernelModules(pkgs, kernel)
+		for _, module := range byKernel[kernel] {
+			if !slices.Contains(all[flavor], module) {
+				all[flavor] = append(all[flavor], module)
+			}
+		}
+	}
+
+	results := make([]kernelModulesResult, 0, len(kernels))
+	for _, kernel := range kernels {
+		result := kernelModulesResult{kernel: pkgs[kernel]}
+		for _, module := range byKernel[kernel] {
+			pkg := pkgs[kernel+"-"+module]
+			item := moduleResult{name: pkg.NAME, version: pkg.VERSION, depend: kernelDepend(pkg, kernel)}
+			// a module is built for one kernel version: "linux612=6.12.20-1"
+			_, mod, _ := alpm.ParseDep(item.depend)
+			switch {
+			case mod == "":
+				item.problem = moduleUnversioned
+			case !alpm.Satisfies(result.kernel.VERSION, item.depend):
+				item.problem = moduleMismatch
+			case mod != "=":
+				item.problem = moduleUnpinned
+			}
+			result.modules = append(result.modules, item)
+		}
+		flavor := alpm.KernelRegex.FindStringSubmatch(kernel)[1]
+		for _, module := range all[flavor] {
+			if !slices.Contains(byKernel[kernel], module) {
+				result.missing = append(result.missing, module)
+			}
+		}
+		slices.Sort(result.missing)
+		results = append(results, result)
+	}
+	return results
+}
+
+// modulesCmd represents the modules command
+var modulesCmd = &cobra.Command{
+	Use:   "modules",
+	Short: tr.T("check extramodules of kernels"),
+	Long: `Extramodules (linuxXY-nvidia, linuxXY-zfs...) of each kernel in branches:
+  built against the kernel version of the branch (DEPENDS linuxXY=version),
+  a dependency without version or not pinned with "=" is also a problem
+  modules of other kernels missing for a kernel
+ex:
+	modules
+	modules -s --all
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		selected := slices.DeleteFunc(FlagBranches.toSlice(), func(b string) bool { return b == "archlinux" })
+		if len(selected) < 1 {
+			selected = conf.Branches
+		}
+
+		problems := 0
+		for _, branch := range selected {
+			pkgs, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+			fmt.Printf("%s%s%s\n", theme.Theme(branch), branch, theme.ColorNone)
+			for _, result := range checkModules(pkgs) {
+				fmt.Printf("  %s%-12s%s %s\n", theme.ColorBold, result.kernel.NAME, theme.ColorNone, result.kernel.VERSION)
+				for _, module := range result.modules {
+					if module.problem != "" {
+						problems++
+					} else if !FlagModulesAll {
+						continue
+					}
+					color := theme.ColorGray
+					switch module.problem {
+					case moduleMismatch:
+						color = theme.ColorUnstable
+					case moduleUnversioned, moduleUnpinned:
+						color = theme.ColorTesting
+					}
+					depend := module.depend
+					if depend == "" {
+						depend = "-"
+					}
+					fmt.Printf("    %-30s %-24s %s %s\n", module.name, module.version,
+						padRightANSI(color+depend+theme.ColorNone, 28), color+module.problem+theme.ColorNone)
+				}
+				if len(result.missing) > 0 {
+					problems += len(result.missing)
+					fmt.Printf("    %s%s: %s%s\n", theme.ColorTesting, tr.T("missing"), strings.Join(result.missing, " "), theme.ColorNone)
+				}
+			}
+			fmt.Println()
+		}
+		fmt.Printf("# %d %s\n", problems, tr.T("problems"))
+		return nil
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(modulesCmd)
+	modulesCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
+	modulesCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
+	modulesCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
+	modulesCmd.Flags().BoolVarP(&FlagModulesAll, "all", "", FlagModulesAll, tr.T("display also modules without problem"))
+}
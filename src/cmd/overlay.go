@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	FlagOverlayPackager string = "manjaro"
+	FlagOverlayShow     []string
+)
+
+// groups of packages in a Manjaro branch, compared to archlinux
+const (
+	overlayIdentical = "identical" // same version and packager as archlinux
+	overlayRebuilt   = "rebuilt"   // same version as archlinux, Manjaro packager
+	overlayOnly      = "only"      // not in archlinux
+	// not one of the three groups, but the total is the branch:
+	// other version than archlinux, not synced or a Manjaro version
+	overlayOther = "other"
+)
+
+var overlayGroups = []string{overlayIdentical, overlayRebuilt, overlayOnly, overlayOther}
+
+// groups are --show values, displayed translated
+var overlayLabels = map[string]string{
+	overlayIdentical: tr.T("identical"),
+	overlayRebuilt:   tr.T("rebuilt"),
+	overlayOnly:      tr.T("only"),
+	overlayOther:     tr.T("other"),
+}
+
+// overlay splits the packages of a branch by group
+func overlay(pkgs, archs alpm.Packages, manjaro *regexp.Regexp) map[string][]*alpm.Package {
+	groups := make(map[string][]*alpm.Package, len(overlayGroups))
+	for name, pkg := range pkgs {
+		group := overlayOnly
+		if arch, ok := archs[name]; ok {
+			switch {
+			case pkg.VERSION != arch.VERSION:
+				group = overlayOther
+			case manjaro.MatchString(pkg.PACKAGER):
+				group = overlayRebuilt
+			case pkg.PACKAGER == arch.PACKAGER:
+				group = overlayIdentical
+			default:
+				// same version, built by another packager
+				group = overlayOther
+			}
+		}
+		groups[group] = append(groups[group], pkg)
+	}
+	for _, list := range groups {
+		sort.Slice(list, func(i, j int) bool { return list[i].NAME < list[j].NAME })
+	}
+	return groups
+}
+
+// overlayCmd represents the overlay command
+var overlayCmd = &cobra.Command{
+	Use:   "overlay",
+	Short: tr.T("packages maintained by Manjaro, compared to archlinux"),
+	Long: `Packages of each Manjaro branch, by group:
+  identical: same version and packager as archlinux
+  rebuilt:   same version as archlinux, built by a Manjaro packager
+  only:      not in archlinux
+"rebuilt" and "only" are the Manjaro overlay.
+Other packages are counted apart, so the total is the branch:
+  other:     in archlinux with another version (not synced yet,
+             or a Manjaro version), or built by another packager
+ex:
+	overlay
+	overlay --show rebuilt,only -s
+	overlay --packager 'manjaro|philm'
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		manjaro, err := regexp.Compile("(?i)" + FlagOverlayPackager)
+		if err != nil {
+			return fmt.Errorf("%s: %s", tr.T("bad regex"), FlagOverlayPackager)
+		}
+		selected := slices.DeleteFunc(FlagBranches.toSlice(), func(b string) bool { return b == "archlinux" })
+		if len(selected) < 1 {
+			selected = conf.Branches
+		}
+
+		archs, _ := alpm.Load(filepath.Join(cacheDir, "archlinux", "sync"), conf.Repos, "archlinux", false)
+		results := make(map[string]map[string][]*alpm.Package, len(selected))
+		for _, branch := range selected {
+			pkgs, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+			results[branch] = overlay(pkgs, archs, manjaro)
+		}
+
+		for _, branch := range selected {
+			for _, group := range FlagOverlayShow {
+				list := results[branch][group]
+				col := 12
+				for _, pkg := range list {
+					col = max(col, len(pkg.NAME)+1)
+				}
+				fmt.Printf("%s%s%s %s (%d)\n", theme.Theme(branch), branch, theme.ColorNone, theme.ColorBold+overlayLabels[group]+theme.ColorNone, len(list))
+				for _, pkg := range list {
+					arch := ""
+					if a, ok := archs[pkg.NAME]; ok && a.VERSION != pkg.VERSION {
+						arch = theme.Theme("archlinux") + a.VERSION + theme.ColorNone
+					}
+					fmt.Printf("  %-"+strconv.Itoa(col)+"s %-10s %-20s %s %s\n", pkg.NAME, pkg.REPO, pkg.VERSION, padRightANSI(arch, 20), grayEmail(pkg.PACKAGER))
+				}
+				fmt.Println()
+			}
+		}
+
+		fmt.Printf("# %-10s", tr.T("branch"))
+		for _, group := range overlayGroups {
+			fmt.Printf(" %10s", overlayLabels[group])
+		}
+		fmt.Printf(" %10s %10s\n", tr.T("overlay"), tr.T("total"))
+		for _, branch := range selected {
+			fmt.Printf("  %s", padRightANSI(theme.Theme(branch)+branch+theme.ColorNone, 10))
+			total := 0
+			for _, group := range overlayGroups {
+				fmt.Printf(" %10d", len(results[branch][group]))
+				total += len(results[branch][group])
+			}
+			own := len(results[branch][overlayRebuilt]) + len(results[branch][overlayOnly])
+			percent := 0.0
+			if total > 0 {
+				percent = float64(own) * 100 / float64(total)
+			}
+			fmt.Printf(" %10s %10d\n", fmt.Sprintf("%d %3.0f%%", own, percent), total)
+		}
+		fmt.Printf("# %s: %s\n", tr.T("Manjaro packagers"), FlagOverlayPackager)
+		return nil
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := onlyFlags(cmd, args); err != nil {
+			return err
+		}
+		for _, group := range FlagOverlayShow {
+			if !slices.Contains(overlayGroups, group) {
+				return fmt.Errorf("%s: %s (%s)", tr.T("invalid group"), group, strings.Join(overlayGroups, ", "))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(overlayCmd)
+	overlayCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
+	overlayCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
+	overlayCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
+	overlayCmd.Flags().StringVarP(&FlagOverlayPackager, "packager", "", FlagOverlayPackager, tr.T("Manjaro packagers (regex)"))
+	overlayCmd.Flags().StringSliceVar(&FlagOverlayShow, "show", nil, tr.T("list packages of groups")+": "+strings.Join(overlayGroups, ", "))
+}
@@ -3,11 +3,14 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"mbc/alpm"
 	"mbc/theme"
 	"mbc/tr"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -15,45 +18,231 @@ import (
 
 var (
 	FlagQuiet  = false
-	FlagInfo   bool
+	FlagInfo   int // -II: extra informations
 	FlagSearch bool
 	FlagList   bool
 )
 
-func execPacman(cmd []string, branch string) {
-	//fmt.Println()
-	fmt.Println(theme.Theme(branch) + branch + theme.Theme(""))
-	fmt.Println()
+// humanSize as pacman: "911.47 KiB"
+func humanSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	unit := 0
+	for (value >= 1024 || value <= -1024) && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f %s", value, units[unit])
+}
+
+// packages sorted as pacman: by repository order, then by name
+func sortedPackages(pkgs alpm.Packages, repos []string) []*alpm.Package {
+	result := make([]*alpm.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		result = append(result, pkg)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		ri, rj := slices.Index(repos, result[i].REPO), slices.Index(repos, result[j].REPO)
+		if ri != rj {
+			return ri < rj
+		}
+		return result[i].NAME < result[j].NAME
+	})
+	return result
+}
+
+// " [installed]" or " [installed: 1.2-3]" as pacman
+func installedMark(pkg *alpm.Package, locals alpm.Packages) string {
+	local, ok := locals[pkg.NAME]
+	if !ok {
+		return ""
+	}
+	if local.VERSION != pkg.VERSION {
+		return theme.ColorBold + " [" + tr.T("installed") + ": " + local.VERSION + "]" + theme.ColorNone
+	}
+	return theme.ColorBold + " [" + tr.T("installed") + "]" + theme.ColorNone
+}
+
+// pacman -Ss: all regex must match name, description or a provide
+func pacmanSearch(pkgs alpm.Packages, repos []string, branch string, terms []string, locals alpm.Packages) error {
+	regs := make([]*regexp.Regexp, 0, len(terms))
+	for _, term := range terms {
+		reg, err := regexp.Compile("(?i)" + term)
+		if err != nil {
+			return fmt.Errorf("%s: %s", tr.T("bad regex"), term)
+		}
+		regs = append(regs, reg)
+	}
+	match := func(pkg *alpm.Package) bool {
+		for _, reg := range regs {
+			found := reg.MatchString(pkg.NAME) || reg.MatchString(pkg.DESC)
+			for _, provide := range pkg.PROVIDES {
+				found = found || reg.MatchString(alpm.DepName(provide))
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, pkg := range sortedPackages(pkgs, repos) {
+		if !match(pkg) {
+			continue
+		}
+		if FlagQuiet {
+			fmt.Println(pkg.NAME)
+			continue
+		}
+		groups := ""
+		if len(pkg.GROUPS) > 0 {
+			groups = " (" + strings.Join(pkg.GROUPS, " ") + ")"
+		}
+		fmt.Printf("%s/%s %s%s%s\n", theme.Theme(branch)+pkg.REPO+theme.ColorNone,
+			theme.ColorBold+pkg.NAME+theme.ColorNone, theme.ColorStable+pkg.VERSION+theme.ColorNone,
+			groups, installedMark(pkg, locals))
+		fmt.Printf("    %s\n", pkg.DESC)
+	}
+	return nil
+}
+
+// pacman -Sl
+func pacmanList(pkgs alpm.Packages, repos []string, branch string, locals alpm.Packages) {
+	for _, pkg := range sortedPackages(pkgs, repos) {
+		if FlagQuiet {
+			fmt.Println(pkg.NAME)
+			continue
+		}
+		fmt.Printf("%s %s %s%s\n", theme.Theme(branch)+pkg.REPO+theme.ColorNone, pkg.NAME, pkg.VERSION, installedMark(pkg, locals))
+	}
+}
+
+// pacman -Si, -Sii (extra) add reverse dependencies
+func pacmanInfo(pkg *alpm.Package, all alpm.Packages, extra bool) {
+	none := func(items []string) string {
+		if len(items) < 1 {
+			return "None"
+		}
+		return strings.Join(items, "  ")
+	}
+	field := func(label, value string) {
+		fmt.Printf("%s%-15s:%s %s\n", theme.ColorBold, label, theme.ColorNone, value)
+	}
 
-	run := exec.Command("/usr/bin/pacman", cmd...) //, "--debug")
-	run.Stdout = os.Stdout
-	err := run.Run()
-	if err != nil {
-		fmt.Println(err)
+	field("Repository", pkg.REPO)
+	field("Name", pkg.NAME)
+	field("Version", pkg.VERSION)
+	field("Description", pkg.DESC)
+	field("Architecture", pkg.ARCH)
+	field("URL", pkg.URL)
+	field("Licenses", none(pkg.LICENSE))
+	field("Groups", none(pkg.GROUPS))
+	field("Provides", none(pkg.PROVIDES))
+	field("Depends On", none(pkg.DEPENDS))
+	if len(pkg.OPTDEPENDS) < 1 {
+		field("Optional Deps", "None")
+	} else {
+		field("Optional Deps", strings.Join(pkg.OPTDEPENDS, "\n"+strings.Repeat(" ", 17)))
+	}
+	if extra {
+		var requiredBy, optionalFor []string
+		for _, other := range all {
+			if slices.ContainsFunc(other.DEPENDS, func(dep string) bool { return alpm.DepName(dep) == pkg.NAME }) {
+				requiredBy = append(requiredBy, other.NAME)
+			}
+			if slices.ContainsFunc(other.OPTDEPENDS, func(dep string) bool { return alpm.DepName(dep) == pkg.NAME }) {
+				optionalFor = append(optionalFor, other.NAME)
+			}
+		}
+		sort.Strings(requiredBy)
+		sort.Strings(optionalFor)
+		field("Required By", none(requiredBy))
+		field("Optional For", none(optionalFor))
 	}
+	field("Conflicts With", none(pkg.CONFLICTS))
+	field("Replaces", none(pkg.REPLACES))
+	field("Download Size", humanSize(pkg.CSIZE))
+	field("Installed Size", humanSize(pkg.ISIZE))
+	field("Packager", pkg.PACKAGER)
+	field("Build Date", pkg.BUILDDATE.Format("Mon 02 Jan 2006 03:04:05 PM MST"))
+	validated := []string{}
+	if pkg.MD5SUM != "" {
+		validated = append(validated, "MD5 Sum")
+	}
+	if pkg.SHA256SUM != "" {
+		validated = append(validated, "SHA-256 Sum")
+	}
+	if pkg.PGPSIG {
+		validated = append(validated, "Signature")
+	}
+	field("Validated By", none(validated))
 	fmt.Println()
+}
+
+func runPacman(config Config, cacheDir string, branch string, args []string) error {
 	fmt.Println(theme.Theme(branch) + branch + theme.Theme(""))
+	fmt.Println()
+	defer func() {
+		fmt.Println(theme.Theme(branch) + branch + theme.Theme(""))
+	}()
+
+	pkgs, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), config.Repos, branch, true)
+	locals := alpm.Packages{}
+	if alpm.LocalDBExists() {
+		locals, _ = alpm.LoadLocal()
+	}
+
+	switch {
+	case FlagSearch:
+		return pacmanSearch(pkgs, config.Repos, branch, args, locals)
+	case FlagList:
+		pacmanList(pkgs, config.Repos, branch, locals)
+	case FlagInfo > 0:
+		if len(args) < 1 {
+			for _, pkg := range sortedPackages(pkgs, config.Repos) {
+				pacmanInfo(pkg, pkgs, FlagInfo > 1)
+			}
+			return nil
+		}
+		for _, arg := range args {
+			name := strings.TrimSpace(strings.ToLower(arg))
+			// as pacman: "repo/name"
+			repo, name, found := strings.Cut(name, "/")
+			if !found {
+				name, repo = repo, ""
+			}
+			pkg, ok := pkgs[name]
+			if !ok || (repo != "" && pkg.REPO != repo) {
+				fmt.Fprintf(os.Stderr, "error: %s '%s'\n", tr.T("package was not found"), arg)
+				continue
+			}
+			pacmanInfo(pkg, pkgs, FlagInfo > 1)
+		}
+	}
+	return nil
 }
 
-// pacmanCmd use the `pacman -S[i|s]`
+// pacmanCmd as `pacman -S[i|s|l]`, without pacman
 var pacmanCmd = &cobra.Command{
 	Use:   "pacman [packageName]",
-	Short: "run pacman in branch",
-	Long: `run a pacman command as:
-pacman -S* --config '~/.cache/` + ApplicationID + `/BRANCH/pacman.conf'
+	Short: tr.T("pacman -S queries in branch"),
+	Long: `pacman -S* queries on the databases of a branch, in the cache directory
+pacman is not required
 Examples in stable branch.
 pacman -Si: Info :
   -Is package_name
-pacman -Ss: Search :
+pacman -Sii: Info with required by and optional for :
+  -IIs package_name
+pacman -Ss: Search (regex in names and descriptions) :
   -Ss text
 pacman -Sl: List :
   -Ls
 	`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
-		cachePath := ctx.Value(ctxCacheDir).(string)
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
 		branch := FlagBranches.toSlice()[0]
-		cachePath = filepath.Join(cachePath, branch, "pacman.conf")
 
 		if len(args) > 0 && args[0] == "-" {
 			args = []string{}
@@ -67,55 +256,27 @@ pacman -Sl: List :
 			}
 		}
 
-		search := ""
-		if len(args) > 0 {
-			search = strings.TrimSpace(strings.ToLower(args[0]))
-		}
-
-		runargs := []string{}
-		if FlagSearch {
-			runargs = []string{"-Ss", search}
-		}
-		if FlagInfo {
-			if len(search) > 0 {
-				runargs = []string{"-Sii"}
-				if FlagQuiet {
-					runargs = []string{"-Si"}
-				}
-				for _, arg := range args {
-					runargs = append(runargs, strings.TrimSpace(strings.ToLower(arg)))
-				}
-			} else {
-				runargs = []string{"-Si"}
-			}
-		}
-		if FlagList {
-			runargs = []string{"-Sl"}
-		}
-		if FlagQuiet {
-			runargs = append(runargs, "-q")
-		}
-		execPacman(append(runargs, "--config", cachePath), branch)
+		return runPacman(conf, cacheDir, branch, args)
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		addSystemBranch(1)
+		return nil
 	},
 }
 
 func init() {
-	if _, err := os.Stat("/usr/bin/pacman"); err == nil {
-		rootCmd.AddCommand(pacmanCmd)
-		pacmanCmd.Short = tr.T(pacmanCmd.Short)
-
-		pacmanCmd.Flags().BoolVarP(&FlagSearch, "Search", "S", false, tr.T("search"))
-		pacmanCmd.Flags().BoolVarP(&FlagList, "List", "L", false, tr.T("list"))
-		pacmanCmd.Flags().BoolVarP(&FlagInfo, "Info", "I", false, tr.T("info"))
-		pacmanCmd.MarkFlagsOneRequired("Search", "List", "Info")
-		pacmanCmd.MarkFlagsMutuallyExclusive("Search", "List", "Info")
-
-		pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
-		pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
-		pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
-		pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagArchlinux, "archlinux", "a", FlagBranches.FlagArchlinux, "archlinux "+tr.T("branch"))
-		pacmanCmd.MarkFlagsOneRequired("stable", "testing", "unstable", "archlinux")
-		pacmanCmd.MarkFlagsMutuallyExclusive("stable", "testing", "unstable", "archlinux")
-		pacmanCmd.Flags().BoolVarP(&FlagQuiet, "quiet", "q", FlagQuiet, tr.T("show less information"))
-	}
+	rootCmd.AddCommand(pacmanCmd)
+
+	pacmanCmd.Flags().BoolVarP(&FlagSearch, "Search", "S", false, tr.T("search"))
+	pacmanCmd.Flags().BoolVarP(&FlagList, "List", "L", false, tr.T("list"))
+	pacmanCmd.Flags().CountVarP(&FlagInfo, "Info", "I", tr.T("info (-II for more)"))
+	pacmanCmd.MarkFlagsOneRequired("Search", "List", "Info")
+	pacmanCmd.MarkFlagsMutuallyExclusive("Search", "List", "Info")
+
+	pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
+	pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
+	pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
+	pacmanCmd.Flags().BoolVarP(&FlagBranches.FlagArchlinux, "archlinux", "a", FlagBranches.FlagArchlinux, "archlinux "+tr.T("branch"))
+	pacmanCmd.MarkFlagsMutuallyExclusive("stable", "testing", "unstable", "archlinux")
+	pacmanCmd.Flags().BoolVarP(&FlagQuiet, "quiet", "q", FlagQuiet, tr.T("show less information"))
 }
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var FlagNoArchlinux bool
+
+type regression struct {
+	name    string
+	repo    string
+	branch  string // newer, but should not be
+	against string // earlier branch, or "previous" snapshot
+	newer   *alpm.Package
+	older   *alpm.Package
+}
+
+// promotion chain: archlinux > unstable > testing > stable
+func promotionChain(branches []string) (chain []string) {
+	if !FlagNoArchlinux {
+		chain = append(chain, "archlinux")
+	}
+	for _, branch := range slices.Backward(branches) {
+		chain = append(chain, branch)
+	}
+	return chain
+}
+
+// regressions: a later branch newer than an earlier branch in chain
+// or a branch older than its previous snapshot
+func regressions(chain []string, current, previous map[string]alpm.Packages) (results []regression) {
+	names := make(map[string]bool)
+	for _, pkgs := range current {
+		for name := range pkgs {
+			names[name] = true
+		}
+	}
+
+	for name := range names {
+		// compare to the last branch in chain with this package
+		var earlier *alpm.Package
+		earlierBranch := ""
+		for _, branch := range chain {
+			pkg, ok := current[branch][name]
+			if !ok {
+				continue
+			}
+			if earlier != nil && alpm.AlpmPkgVerCmp(pkg.VERSION, earlier.VERSION) > 0 {
+				results = append(results, regression{name, pkg.REPO, branch, earlierBranch, pkg, earlier})
+			}
+			earlier, earlierBranch = pkg, branch
+		}
+
+		for _, branch := range chain {
+			pkg, ok := current[branch][name]
+			if !ok {
+				continue
+			}
+			if old, ok := previous[branch][name]; ok && alpm.AlpmPkgVerCmp(old.VERSION, pkg.VERSION) > 0 {
+				results = append(results, regression{name, pkg.REPO, "previous", branch, old, pkg})
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].name != results[j].name {
+			return results[i].name < results[j].name
+		}
+		return results[i].branch < results[j].branch
+	})
+	return results
+}
+
+// regressionsCmd represents the regressions command
+var regressionsCmd = &cobra.Command{
+	Use:   "regressions",
+	Short: tr.T("downgrades along the branch chain"),
+	Long: `Walk the promotion chain: archlinux, unstable, testing, stable
+Lists packages:
+  newer in a later branch than in an earlier one (ex: stable newer than testing)
+  older in a branch than in its previous snapshot (database before the last update)
+with build dates
+ex:
+	regressions
+	regressions --no-archlinux
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		chain := promotionChain(conf.Branches)
+		current := make(map[string]alpm.Packages, len(chain))
+		previous := make(map[string]alpm.Packages, len(chain))
+		for _, branch := range chain {
+			current[branch], _ = alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+			if _, err := os.Stat(_getPreviousDir(cacheDir, branch)); err == nil {
+				previous[branch], _ = alpm.Load(_getPreviousDir(cacheDir, branch), conf.Repos, branch, false)
+			}
+		}
+
+		results := regressions(chain, current, previous)
+
+		col := 12
+		for _, r := range results {
+			col = max(col, len(r.name)+1)
+		}
+		color := func(branch string) string {
+			return theme.Theme(branch) + branch + theme.ColorNone
+		}
+		date := func(pkg *alpm.Package) string {
+			return theme.ColorGray + pkg.BUILDDATE.Format("06-01-02 15:04") + theme.ColorNone
+		}
+
+		fmt.Printf("# %-"+strconv.Itoa(col-2)+"s %-12s %-30s %s\n", tr.T("package"), tr.T("repository"), tr.T("newer"), tr.T("older"))
+		for _, r := range results {
+			newer := padRightANSI(color(r.branch)+" "+r.newer.VERSION, 30)
+			older := color(r.against) + " " + r.older.VERSION
+			fmt.Printf("%-"+strconv.Itoa(col)+"s %-12s %s %s\n", r.name, r.repo, newer, older)
+			fmt.Printf("%-"+strconv.Itoa(col)+"s %-12s %s %s\n", "", "", padRightANSI(date(r.newer), 30), date(r.older))
+		}
+		fmt.Println()
+		fmt.Printf("# %d %s\n", len(results), tr.T("regressions"))
+		for _, branch := range chain {
+			if len(previous[branch]) < 1 {
+				fmt.Printf("# %s: %s\n", color(branch), tr.T("no previous snapshot, run update"))
+			}
+		}
+		return nil
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(regressionsCmd)
+	regressionsCmd.Flags().BoolVarP(&FlagNoArchlinux, "no-archlinux", "", FlagNoArchlinux, tr.T("chain without archlinux"))
+}
@@ -5,24 +5,53 @@ import (
 	"mbc/tr"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// _getCacheFiles: files and directories created by mbc in the cache directory,
+// the cache directory can be any directory of the user (cache_dir, --cache-dir)
+func _getCacheFiles(cacheDir string, branches []string) []string {
+	files := []string{_getDateFile(cacheDir), _getWatchFile(cacheDir), filepath.Join(cacheDir, "kernel.org", "releases.json")}
+	responses, _ := filepath.Glob(filepath.Join(cacheDir, "ai", "*.txt"))
+	files = append(files, responses...)
+	for _, branch := range branches {
+		// pacman.conf of older versions
+		files = append(files, filepath.Join(cacheDir, branch, "sync"), _getPreviousDir(cacheDir, branch), filepath.Join(cacheDir, branch, "pacman.conf"))
+	}
+	return files
+}
+
 // rmCmd represents the rm command
 var rmCmd = &cobra.Command{
 	Use:   "rm",
-	Short: "remove database in" + " ~/.cache/",
+	Short: tr.T("remove databases and cache files"),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
 		cacheDir := ctx.Value(ctxCacheDir).(string)
 		confFilename := ctx.Value(ctxConfFilename).(string)
-		err := os.RemoveAll(cacheDir)
-		if err != nil {
-			fmt.Println(err)
-		} else {
-			fmt.Println(tr.T("Directory"), cacheDir, tr.T("removed successfully"))
+		branches := append(slices.Clone(conf.Branches), "archlinux")
+		failed := false
+		for _, file := range _getCacheFiles(cacheDir, branches) {
+			if err := os.RemoveAll(file); err != nil {
+				fmt.Println(err)
+				failed = true
+			}
+		}
+		// directories only removed if empty, other files are not ours
+		for _, dir := range append(branches, "ai", "kernel.org") {
+			os.Remove(filepath.Join(cacheDir, dir))
+		}
+		os.Remove(filepath.Join(cacheDir, ".lock"))
+		if !failed {
+			if os.Remove(cacheDir) == nil {
+				fmt.Println(tr.T("Directory"), cacheDir, tr.T("removed successfully"))
+			} else {
+				fmt.Println(tr.T("Databases removed from"), cacheDir)
+			}
 		}
 
 		execPath, err := os.Executable()
@@ -53,5 +82,4 @@ var rmCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(rmCmd)
-	rmCmd.Short = tr.T("remove database in") + " ~/.cache/"
 }
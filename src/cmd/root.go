@@ -4,9 +4,15 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"mbc/ai"
+	"mbc/alpm"
+	"mbc/tr"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -20,6 +26,7 @@ type ctxkey int
 const (
 	AutoUpdate    int = 2
 	ApplicationID     = "manjaro-branch-check"
+	LockTimeout       = 2 * time.Minute
 )
 const (
 	ctxConfigVars ctxkey = iota
@@ -28,23 +35,118 @@ const (
 )
 
 type Config struct {
+	Branches []string    `yaml:"branches"`
+	Arch     []string    `yaml:"arch"`
+	Repos    []string    `yaml:"repos"`
+	Urls     []string    `yaml:"urls"`
+	API      ai.Settings `yaml:"ai,omitempty"`
+	CacheDir string      `yaml:"cache_dir,omitempty"`
+	// packages not displayed by diff
+	Excludes []ExcludeRule `yaml:"excludes,omitempty"`
+	// packages reported by watch
+	Watch WatchConfig `yaml:"watch,omitempty"`
+}
+
+// WatchConfig: packages of interest, notified by `watch` on version change
+type WatchConfig struct {
+	// names or regex, a pattern matches the whole name
+	Packages []string `yaml:"packages"`
+	// stdout, notify-send or command
+	Notify []string `yaml:"notify,omitempty"`
+	// shell command, receives the changes in json on stdin
+	Command string `yaml:"command,omitempty"`
+	regexps []*regexp.Regexp
+}
+
+func (w *WatchConfig) compile() error {
+	w.regexps = make([]*regexp.Regexp, 0, len(w.Packages))
+	for _, pattern := range w.Packages {
+		reg, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("watch: %w", err)
+		}
+		w.regexps = append(w.regexps, reg)
+	}
+	return nil
+}
+
+// Match a package name with one of the patterns
+func (w WatchConfig) Match(name string) bool {
+	for _, reg := range w.regexps {
+		if reg.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeRule hides, in diff, packages unique to one of Branches
+type ExcludeRule struct {
+	Name     string   `yaml:"name"`
+	Reason   string   `yaml:"reason,omitempty"`
 	Branches []string `yaml:"branches"`
-	Arch     []string `yaml:"arch"`
-	Repos    []string `yaml:"repos"`
-	Urls     []string `yaml:"urls"`
-	API      string   `yaml:"ai,omitempty"`
+	// compared to these branches, empty for all
+	Against  []string `yaml:"against,omitempty"`
+	Patterns []string `yaml:"patterns"`
+	regexps  []*regexp.Regexp
 }
 
-func (c Config) cache() string {
+// compile patterns, a pattern matches the beginning of the name
+func (r *ExcludeRule) compile() error {
+	r.regexps = make([]*regexp.Regexp, 0, len(r.Patterns))
+	for _, pattern := range r.Patterns {
+		reg, err := regexp.Compile("^(?:" + pattern + ")")
+		if err != nil {
+			return fmt.Errorf("exclude %s: %w", r.Name, err)
+		}
+		r.regexps = append(r.regexps, reg)
+	}
+	return nil
+}
+
+// Match package name only in branch, not in against
+func (r ExcludeRule) Match(branch, against, name string) bool {
+	if !slices.Contains(r.Branches, branch) {
+		return false
+	}
+	if len(r.Against) > 0 && !slices.Contains(r.Against, against) {
+		return false
+	}
+	for _, reg := range r.regexps {
+		if reg.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// xdgDir returns $env or ~/fallback, as in XDG Base Directory specification
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
 	h, _ := os.UserHomeDir()
-	os.MkdirAll(filepath.Join(h, ".cache"), os.ModePerm)
-	return filepath.Join(h, ".cache", ApplicationID)
+	return filepath.Join(h, fallback)
+}
+
+// expandHome replaces a leading "~" by the user home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		h, _ := os.UserHomeDir()
+		return filepath.Join(h, path[1:])
+	}
+	return path
+}
+
+func (c Config) cache() string {
+	if c.CacheDir != "" {
+		return filepath.Clean(expandHome(os.ExpandEnv(c.CacheDir)))
+	}
+	return filepath.Join(xdgDir("XDG_CACHE_HOME", ".cache"), ApplicationID)
 }
 
 func (c Config) configFile() string {
-	h, _ := os.UserHomeDir()
-	os.MkdirAll(filepath.Join(h, ".config"), os.ModePerm)
-	return filepath.Join(h, ".config", ApplicationID+".yaml")
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), ApplicationID+".yaml")
 }
 
 type AppConfig struct {
@@ -52,34 +154,57 @@ type AppConfig struct {
 	Config Config
 }
 
-var AppState = &AppConfig{}
+var (
+	AppState        = &AppConfig{}
+	FlagCacheDir    string
+	FlagLockTimeout time.Duration
+	FlagRoot        string
+	FlagDBPath      string
+)
+
+// loaded configurations, a file is read once by run
+var configs = map[string]*Config{}
 
+// loadConfig reads the configuration file, or the default configuration if the file does not exist
+// the default is not written: loadConfig runs at init, for every command
 func loadConfig(confFilename string) (*Config, error) {
-	file, err := os.Open(confFilename)
-	if err != nil {
-		conf, _ := embedFS.ReadFile("config.yaml")
-		f, err := os.Create(confFilename)
-		if err != nil {
-			return nil, err
-		}
-		defer f.Close()
-		f.WriteString(string(conf))
-		fmt.Printf("# Create file Config : %s\n", confFilename)
+	if config, ok := configs[confFilename]; ok {
+		loaded := *config
+		return &loaded, nil
+	}
+
+	var data []byte
+	if _, err := os.Stat(confFilename); err != nil {
+		data, _ = embedFS.ReadFile("config.yaml")
+	} else if data, err = os.ReadFile(confFilename); err != nil {
+		return nil, err
 	}
-	file.Close()
 
 	var config Config
-	file, err = os.Open(confFilename)
-	if err != nil {
+	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
+
+	if config.Excludes == nil {
+		// old configuration file, use default rules
+		var defaults Config
+		conf, _ := embedFS.ReadFile("config.yaml")
+		if err := yaml.Unmarshal(conf, &defaults); err == nil {
+			config.Excludes = defaults.Excludes
+		}
+	}
+	for i := range config.Excludes {
+		if err := config.Excludes[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.Watch.compile(); err != nil {
 		return nil, err
 	}
 
-	return &config, nil
+	configs[confFilename] = &config
+	loaded := config
+	return &loaded, nil
 }
 
 func cacheIsValid(config Config, cacheDir string) error {
@@ -112,6 +237,9 @@ use four branches with same config (archlinux, unstable, testing, stable)
 Which packages are new to a branch? (diff)
 Which packages disappear? (diff)
 What are the version differences between branches? (info, version)
+
+Without a first branch, commands use the branch of this system
+(in /etc/pacman.d/mirrorlist or /etc/pacman-mirrors.conf)
 `,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		confFilename := Config{}.configFile()
@@ -120,21 +248,60 @@ What are the version differences between branches? (info, version)
 			fmt.Fprintln(os.Stderr, "Error loading yaml configuration", confFilename)
 			return err
 		}
+		if FlagCacheDir != "" {
+			conf.CacheDir = FlagCacheDir
+		}
+		alpm.SetPaths(FlagRoot, FlagDBPath)
 		ctx := context.Background()
 		ctx = context.WithValue(ctx, ctxConfigVars, *conf)
 		ctx = context.WithValue(ctx, ctxCacheDir, conf.cache())
 		ctx = context.WithValue(ctx, ctxConfFilename, confFilename)
 		cmd.SetContext(ctx)
-		if !(strings.HasPrefix(cmd.Use, "help") || strings.HasPrefix(cmd.Use, "update")) {
-			return cacheIsValid(*conf, ctx.Value(ctxCacheDir).(string))
+		if strings.HasPrefix(cmd.Use, "help") || strings.HasPrefix(cmd.Use, "update") {
+			return nil
 		}
-		return err
+		// update takes its own exclusive lock, rm needs it too
+		cacheLocker, err = lockCache(conf.cache(), strings.HasPrefix(cmd.Use, "rm"), FlagLockTimeout)
+		if err != nil {
+			return err
+		}
+		return cacheIsValid(*conf, ctx.Value(ctxCacheDir).(string))
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		cacheLocker.unlock()
+		cacheLocker = nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&FlagCacheDir, "cache-dir", "", "", tr.T("databases directory (default $XDG_CACHE_HOME)"))
+	rootCmd.PersistentFlags().DurationVarP(&FlagLockTimeout, "lock-timeout", "", LockTimeout, tr.T("wait for the cache lock"))
+	rootCmd.PersistentFlags().StringVarP(&FlagRoot, "root", "", "", tr.T("installation root, as pacman"))
+	rootCmd.PersistentFlags().StringVarP(&FlagDBPath, "dbpath", "", "", tr.T("pacman database path (default /var/lib/pacman)"))
+	rootCmd.PersistentFlags().StringVarP(&alpm.MIRRORSCONF, "mirrors-conf", "", alpm.MIRRORSCONF, tr.T("pacman-mirrors configuration, for the system branch"))
+	rootCmd.PersistentFlags().StringVarP(&alpm.MIRRORLIST, "mirrorlist", "", alpm.MIRRORLIST, tr.T("pacman mirrorlist, for the system branch"))
+}
+
+// onlyFlags: cobra Args of commands without argument
+func onlyFlags(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%s: %v", tr.T("use only flags! too much"), args)
+	}
+	return nil
+}
+
+// twoBranches: the two compared branches, the system branch if one is missing
+func twoBranches() error {
+	addSystemBranch(2)
+	if FlagBranches.count() != 2 {
+		return fmt.Errorf("%s: %d", tr.T("invalid branches specified, want"), 2)
+	}
+	return nil
+}
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	FlagStaleBranch   branchNaneFlagType
+	FlagOlderThan     = ageFlagType{value: 365 * 24 * time.Hour}
+	FlagLag           bool
+	FlagLagVersions   int
+	FlagStalePackager string
+)
+
+// ageFlagType: duration with days and weeks, as "365d", "2w" or "48h"
+type ageFlagType struct {
+	value time.Duration
+	text  string
+}
+
+func (a *ageFlagType) String() string {
+	if a.text == "" && a.value == 0 {
+		return ""
+	}
+	if a.text == "" {
+		return strconv.Itoa(int(a.value.Hours()/24)) + "d"
+	}
+	return a.text
+}
+
+func (a *ageFlagType) Set(v string) error {
+	v = strings.TrimSpace(strings.ToLower(v))
+	if v == "" {
+		return errors.New(tr.T("invalid age, ex: 365d, 8w, 48h"))
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[v[len(v)-1]]; ok {
+		n, err := strconv.Atoi(v[:len(v)-1])
+		if err != nil || n < 0 {
+			return errors.New(tr.T("invalid age, ex: 365d, 8w, 48h"))
+		}
+		a.value, a.text = time.Duration(n)*unit, v
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return errors.New(tr.T("invalid age, ex: 365d, 8w, 48h"))
+	}
+	a.value, a.text = d, v
+	return nil
+}
+
+func (a *ageFlagType) Type() string {
+	return "age"
+}
+
+func ageDays(date time.Time) int {
+	return int(time.Since(date).Hours() / 24)
+}
+
+type staleResult struct {
+	pkg      *alpm.Package
+	arch     *alpm.Package // lag mode
+	days     int
+	versions int // lag mode
+}
+
+// stale: packages built before now - age, oldest first
+func stale(pkgs alpm.Packages, age time.Duration) (results []staleResult) {
+	limit := time.Now().Add(-age)
+	for _, pkg := range pkgs {
+		if pkg.BUILDDATE.Before(limit) {
+			results = append(results, staleResult{pkg, nil, ageDays(pkg.BUILDDATE), 0})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].days != results[j].days {
+			return results[i].days > results[j].days
+		}
+		return results[i].pkg.NAME < results[j].pkg.NAME
+	})
+	return results
+}
+
+// lag: packages older than in archlinux, for days since the archlinux build
+// or by versions; without limits, all packages behind archlinux
+func lag(pkgs, archs alpm.Packages, age time.Duration, versions int) (results []staleResult) {
+	for name, pkg := range pkgs {
+		arch, ok := archs[name]
+		if !ok || alpm.AlpmPkgVerCmp(pkg.VERSION, arch.VERSION) >= 0 {
+			continue
+		}
+		r := staleResult{pkg, arch, ageDays(arch.BUILDDATE), alpm.VersionDistance(pkg.VERSION, arch.VERSION)}
+		byAge := age > 0 && time.Since(arch.BUILDDATE) >= age
+		byVersions := versions > 0 && r.versions >= versions
+		if (age > 0 || versions > 0) && !byAge && !byVersions {
+			continue
+		}
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i].pkg, results[j].pkg
+		if a.PACKAGER != b.PACKAGER {
+			return a.PACKAGER < b.PACKAGER
+		}
+		if a.REPO != b.REPO {
+			return a.REPO < b.REPO
+		}
+		if results[i].days != results[j].days {
+			return results[i].days > results[j].days
+		}
+		return a.NAME < b.NAME
+	})
+	return results
+}
+
+// staleCmd represents the stale command
+var staleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: tr.T("old packages in a branch"),
+	Long: `Packages of a branch by build age, oldest first
+
+with --lag, packages behind archlinux, by packager and repository:
+  days since the build in archlinux (--older-than, if set)
+  or versions behind archlinux (--versions)
+ex:
+	stale --branch s --older-than 365d
+	stale --branch u --lag --older-than 4w
+	stale --lag --versions 3 --packager manjaro
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		branch := FlagStaleBranch.value
+		if branch == "" {
+			branch = systemBranch(conf.Branches)
+		}
+		pkgs, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+		if FlagStalePackager != "" {
+			for name, pkg := range pkgs {
+				if !strings.Contains(strings.ToLower(pkg.PACKAGER), strings.ToLower(FlagStalePackager)) {
+					delete(pkgs, name)
+				}
+			}
+		}
+
+		var results []staleResult
+		if FlagLag {
+			archs, _ := alpm.Load(filepath.Join(cacheDir, "archlinux", "sync"), conf.Repos, "archlinux", false)
+			age := time.Duration(0)
+			if cmd.Flags().Changed("older-than") {
+				age = FlagOlderThan.value
+			}
+			results = lag(pkgs, archs, age, FlagLagVersions)
+		} else {
+			results = stale(pkgs, FlagOlderThan.value)
+		}
+
+		col := 12
+		for _, r := range results {
+			col = max(col, len(r.pkg.NAME)+1)
+		}
+		date := func(pkg *alpm.Package) string {
+			return pkg.BUILDDATE.Format("06-01-02 15:04")
+		}
+
+		if !FlagLag {
+			fmt.Printf("# %-"+strconv.Itoa(col-2)+"s %-12s %-20s %-15s %6s  %s\n", tr.T("package"), tr.T("repository"), tr.T("version"), tr.T("date"), tr.T("days"), tr.T("packager"))
+			for _, r := range results {
+				fmt.Printf("%-"+strconv.Itoa(col)+"s %-12s %-20s %-15s %6d  %s\n", r.pkg.NAME, r.pkg.REPO, r.pkg.VERSION, date(r.pkg), r.days, grayEmail(r.pkg.PACKAGER))
+			}
+		} else {
+			packager, repo := "", ""
+			for _, r := range results {
+				if r.pkg.PACKAGER != packager {
+					packager, repo = r.pkg.PACKAGER, ""
+					fmt.Println()
+					fmt.Println(theme.ColorBold + grayEmail(packager) + theme.ColorNone)
+				}
+				if r.pkg.REPO != repo {
+					repo = r.pkg.REPO
+					fmt.Println("  " + repo)
+				}
+				arch := padRightANSI(theme.Theme("archlinux")+r.arch.VERSION+theme.ColorNone, 20)
+				fmt.Printf("    %-"+strconv.Itoa(col)+"s %-20s %s %s%s%s %5d %s, %d %s\n", r.pkg.NAME, r.pkg.VERSION, arch,
+					theme.ColorGray, date(r.arch), theme.ColorNone, r.days, tr.T("days"), r.versions, tr.T("versions"))
+			}
+		}
+		fmt.Println()
+		fmt.Printf("# %s: %s, %d %s\n", tr.T("branch"), theme.Theme(branch)+branch+theme.ColorNone, len(results), tr.T("packages"))
+		if !FlagLag || cmd.Flags().Changed("older-than") {
+			fmt.Printf("# %s: %s\n", tr.T("older than"), FlagOlderThan.String())
+		}
+		return nil
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(staleCmd)
+	FlagStaleBranch = branchNaneFlagType{
+		value:  "",
+		valids: configBranches(),
+	}
+	staleCmd.Flags().Var(&FlagStaleBranch, "branch", tr.T("branch, default the system branch"))
+	staleCmd.Flags().Var(&FlagOlderThan, "older-than", tr.T("build age, ex: 365d, 8w, 48h"))
+	staleCmd.Flags().BoolVarP(&FlagLag, "lag", "", FlagLag, tr.T("packages behind archlinux"))
+	staleCmd.Flags().IntVarP(&FlagLagVersions, "versions", "", 0, tr.T("with --lag, at least N versions behind"))
+	staleCmd.Flags().StringVarP(&FlagStalePackager, "packager", "", "", tr.T("packager filter"))
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mbc/ai"
+	"mbc/alpm"
+	"mbc/tr"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	FlagSummaryPrompt      string
+	FlagSummaryPrintPrompt bool
+)
+
+type summaryPackage struct {
+	Name       string
+	OldName    string // renamed or replaced
+	Repo       string
+	Version    string
+	OldVersion string
+	Change     string
+	Desc       string
+	URL        string
+}
+
+// data of the summary template
+type summaryData struct {
+	From    string
+	To      string
+	Lang    string
+	Kernels []summaryPackage
+	Majors  []summaryPackage // epoch and major
+	Minors  []summaryPackage
+	New     []summaryPackage
+	Renamed []summaryPackage
+	Removed []summaryPackage
+	Counts  map[string]int // upgrades by kind of change
+}
+
+// summary of an update from branches[0] to branches[1], with the diff and version data
+func summary(diffs []diffResult, pkgs [2]alpm.Packages, branches []string) (data summaryData) {
+	data = summaryData{From: branches[0], To: branches[1], Lang: os.Getenv("LANG"), Counts: make(map[string]int)}
+	item := func(pkg *alpm.Package) summaryPackage {
+		return summaryPackage{Name: pkg.NAME, Repo: pkg.REPO, Version: pkg.VERSION, Desc: pkg.DESC, URL: pkg.URL}
+	}
+
+	for _, d := range diffs {
+		switch {
+		case d.link != "":
+			p := item(pkgs[1][d.second])
+			p.OldName, p.OldVersion = d.first, pkgs[0][d.first].VERSION
+			data.Renamed = append(data.Renamed, p)
+		case d.first == "":
+			data.New = append(data.New, item(pkgs[1][d.second]))
+		default:
+			data.Removed = append(data.Removed, item(pkgs[0][d.first]))
+		}
+	}
+
+	for name, old := range pkgs[0] {
+		pkg, ok := pkgs[1][name]
+		if !ok || alpm.AlpmPkgVerCmp(old.VERSION, pkg.VERSION) >= 0 {
+			continue
+		}
+		p := item(pkg)
+		p.OldVersion, p.Change = old.VERSION, alpm.ClassifyChange(old.VERSION, pkg.VERSION)
+		data.Counts[p.Change]++
+		switch {
+		case alpm.KernelRegex.MatchString(name):
+			data.Kernels = append(data.Kernels, p)
+		case p.Change == alpm.ChangeEpoch || p.Change == alpm.ChangeMajor:
+			data.Majors = append(data.Majors, p)
+		case p.Change == alpm.ChangeMinor:
+			data.Minors = append(data.Minors, p)
+		}
+	}
+	for _, list := range [][]summaryPackage{data.Kernels, data.Majors, data.Minors} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return data
+}
+
+// summarizeCmd represents the summarize command
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize",
+	Short: tr.T("AI draft of a branch update announcement"),
+	Long: `Draft of an update announcement by the configured AI,
+with new, removed, renamed packages, kernels and major upgrades
+between two branches.
+
+The prompt is a Go template (text/template), the default is displayed
+with --print-prompt. Replace it with --prompt-file or in configuration:
+  ai:
+    prompts:
+      summary: "~/.config/mbc-summary.tmpl"
+ex:
+	summarize -st
+	summarize -tu --print-prompt
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+		branches = FlagBranches.toSlice()
+
+		autoUpdate(cmd, cacheDir)
+
+		var diffs []diffResult
+		_, _, _, pkgs, _ := diff(&diffs, conf, cacheDir, branches, true)
+		data := summary(diffs, pkgs, branches)
+
+		file := FlagSummaryPrompt
+		if file == "" {
+			file = conf.API.Prompts["summary"]
+		}
+		template, err := ai.LoadPrompt(expandHome(file), ai.SummaryPrompt)
+		if err != nil {
+			return err
+		}
+		prompt, err := ai.Render(template, data)
+		if err != nil {
+			return err
+		}
+		if FlagSummaryPrintPrompt {
+			fmt.Println(prompt)
+			return nil
+		}
+
+		a := ai.MakeAi(conf.API)
+		if a == nil {
+			return errors.New(tr.T("no AI configured, see `ai:` in configuration"))
+		}
+		cache := ai.Cache{Dir: filepath.Join(cacheDir, "ai"), TTL: conf.API.CacheTTL, Refresh: FlagAIRefresh}
+		response, err := ai.AskCached(a, cache, prompt, "summary", branches[0], branches[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(response)
+		return nil
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := onlyFlags(cmd, args); err != nil {
+			return err
+		}
+		return twoBranches()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(summarizeCmd)
+	summarizeCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
+	summarizeCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
+	summarizeCmd.Flags().BoolVarP(&FlagBranches.FlagUnstable, "unstable", "u", FlagBranches.FlagUnstable, "unstable "+tr.T("branch"))
+	summarizeCmd.Flags().BoolVarP(&FlagBranches.FlagArchlinux, "archlinux", "a", FlagBranches.FlagArchlinux, "archlinux "+tr.T("branch"))
+	summarizeCmd.Flags().StringVarP(&FlagSummaryPrompt, "prompt-file", "", "", tr.T("prompt template `file`"))
+	summarizeCmd.Flags().BoolVarP(&FlagSummaryPrintPrompt, "print-prompt", "", FlagSummaryPrintPrompt, tr.T("display the prompt, do not ask the AI"))
+	summarizeCmd.Flags().BoolVarP(&FlagAIRefresh, "ai-refresh", "", FlagAIRefresh, tr.T("ask again, do not use the cached response"))
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var FlagSwitchTo branchNaneFlagType
+
+type switchResult struct {
+	name   string
+	local  string
+	target string
+	delta  int64 // installed size
+	csize  int64 // download size
+}
+
+type switchPreview struct {
+	upgrades   []switchResult
+	downgrades []switchResult
+	foreigns   []switchResult // not in branch, kept installed
+	replaced   []switchResult // not in branch, replaced by target: "name version"
+	news       []switchResult
+	ignored    int // already foreign
+}
+
+// providers of a dependency name in packages
+func findProvider(pkgs alpm.Packages, name string) *alpm.Package {
+	if pkg, ok := pkgs[name]; ok {
+		return pkg
+	}
+	candidates := []string{}
+	for _, pkg := range pkgs {
+		for _, provide := range pkg.PROVIDES {
+			if alpm.DepName(provide) == name {
+				candidates = append(candidates, pkg.NAME)
+			}
+		}
+	}
+	if len(candidates) < 1 {
+		return nil
+	}
+	sort.Strings(candidates)
+	return pkgs[candidates[0]]
+}
+
+// replacer in packages of a local package, as pacman -Su: REPLACES=name[<version]
+func findReplacer(pkgs alpm.Packages, local *alpm.Package) *alpm.Package {
+	candidates := []string{}
+	for _, pkg := range pkgs {
+		for _, replace := range pkg.REPLACES {
+			if alpm.DepName(replace) == local.NAME && alpm.Satisfies(local.VERSION, replace) {
+				candidates = append(candidates, pkg.NAME)
+			}
+		}
+	}
+	if len(candidates) < 1 {
+		return nil
+	}
+	sort.Strings(candidates)
+	return pkgs[candidates[0]]
+}
+
+// what pacman -Syuu does on this system with branch databases
+func switchBranch(locals alpm.Packages, target alpm.Packages, others []alpm.Packages) (preview switchPreview) {
+	// installed after switch: names and provides
+	installed := make(map[string]bool)
+	for name, local := range locals {
+		pkg, ok := target[name]
+		if !ok {
+			pkg = local
+		}
+		installed[name] = true
+		for _, provide := range pkg.PROVIDES {
+			installed[alpm.DepName(provide)] = true
+		}
+	}
+
+	queue := []*alpm.Package{}
+	for name, local := range locals {
+		pkg, ok := target[name]
+		if !ok {
+			if replacer := findReplacer(target, local); replacer != nil {
+				// removed by pacman, the replacer is installed if not already
+				result := switchResult{name, local.VERSION, replacer.NAME + " " + replacer.VERSION, -local.SIZE, 0}
+				if _, ok := locals[replacer.NAME]; !ok && !installed[replacer.NAME] {
+					result.delta += replacer.ISIZE
+					result.csize = replacer.CSIZE
+					installed[replacer.NAME] = true
+					for _, provide := range replacer.PROVIDES {
+						installed[alpm.DepName(provide)] = true
+					}
+					queue = append(queue, replacer)
+				}
+				preview.replaced = append(preview.replaced, result)
+				continue
+			}
+			if len(alpm.Packages{name: local}.Foreign(others...)) > 0 {
+				// aur or local build, not in a branch
+				preview.ignored++
+				continue
+			}
+			// pacman -Syuu does not remove it, the package becomes foreign
+			preview.foreigns = append(preview.foreigns, switchResult{name, local.VERSION, "", local.SIZE, 0})
+			continue
+		}
+		queue = append(queue, pkg)
+		switch alpm.AlpmPkgVerCmp(local.VERSION, pkg.VERSION) {
+		case -1:
+			preview.upgrades = append(preview.upgrades, switchResult{name, local.VERSION, pkg.VERSION, pkg.ISIZE - local.SIZE, pkg.CSIZE})
+		case 1:
+			preview.downgrades = append(preview.downgrades, switchResult{name, local.VERSION, pkg.VERSION, pkg.ISIZE - local.SIZE, pkg.CSIZE})
+		}
+	}
+
+	// new dependencies, and their dependencies
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, dep := range pkg.DEPENDS {
+			name := alpm.DepName(dep)
+			if installed[name] {
+				continue
+			}
+			provider := findProvider(target, name)
+			if provider == nil {
+				continue
+			}
+			installed[name] = true
+			installed[provider.NAME] = true
+			for _, provide := range provider.PROVIDES {
+				installed[alpm.DepName(provide)] = true
+			}
+			preview.news = append(preview.news, switchResult{provider.NAME, "", provider.VERSION, provider.ISIZE, provider.CSIZE})
+			queue = append(queue, provider)
+		}
+	}
+
+	for _, results := range [][]switchResult{preview.upgrades, preview.downgrades, preview.foreigns, preview.replaced, preview.news} {
+		sort.Slice(results, func(i, j int) bool { return results[i].name < results[j].name })
+	}
+	return preview
+}
+
+func signedSize(size int64) string {
+	if size > 0 {
+		return "+" + humanSize(size)
+	}
+	return humanSize(size)
+}
+
+// switchPreviewCmd represents the switch-preview command
+var switchPreviewCmd = &cobra.Command{
+	Use:   "switch-preview",
+	Short: tr.T("preview a switch of this system to another branch"),
+	Long: `Compare installed packages to a branch, before:
+  pacman-mirrors --api --set-branch BRANCH && pacman -Syyuu
+
+Lists packages upgraded, downgraded, new dependencies with sizes,
+packages not in branch replaced by a package of branch (REPLACES),
+and other packages not in branch: pacman keeps them, they become foreign.
+ex:
+	switch-preview --to testing
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		autoUpdate(cmd, cacheDir)
+
+		locals, err := alpm.LoadLocal()
+		if err != nil {
+			return err
+		}
+		branch := FlagSwitchTo.value
+		target, _ := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+		others := []alpm.Packages{target}
+		for _, other := range append(conf.Branches, "archlinux") {
+			if other != branch {
+				pkgs, _ := alpm.Load(filepath.Join(cacheDir, other, "sync"), conf.Repos, other, false)
+				others = append(others, pkgs)
+			}
+		}
+
+		preview := switchBranch(locals, target, others)
+
+		col := 12
+		for _, results := range [][]switchResult{preview.upgrades, preview.downgrades, preview.foreigns, preview.replaced, preview.news} {
+			for _, r := range results {
+				col = max(col, len(r.name)+1)
+			}
+		}
+		var download, installed int64
+		sections := []struct {
+			title   string
+			results []switchResult
+			sum     bool // in download and net upgrade sizes
+			diff    bool // target is a version of local
+		}{
+			{tr.T("Upgraded"), preview.upgrades, true, true},
+			{tr.T("Downgraded"), preview.downgrades, true, true},
+			{tr.T("Replaced"), preview.replaced, true, false},
+			{tr.T("New dependencies"), preview.news, true, false},
+			{tr.T("Would become foreign (not in branch)"), preview.foreigns, false, false},
+		}
+		for _, section := range sections {
+			if len(section.results) < 1 {
+				continue
+			}
+			fmt.Printf("%s%s%s (%d)\n", theme.ColorBold, section.title, theme.ColorNone, len(section.results))
+			for _, r := range section.results {
+				target := r.target
+				if section.diff {
+					target = highlightDiff(r.local, r.target, theme.Theme(branch))
+				}
+				size := humanSize(r.delta)
+				if section.sum {
+					size = signedSize(r.delta)
+					download += r.csize
+					installed += r.delta
+				}
+				fmt.Printf("  %-"+strconv.Itoa(col)+"s %-20s %s %s\n", r.name, r.local, padRightANSI(target, 20), theme.ColorGray+size+theme.ColorNone)
+			}
+			fmt.Println()
+		}
+
+		fmt.Printf("# %s: %s%s%s\n", tr.T("branch"), theme.Theme(branch), branch, theme.ColorNone)
+		fmt.Printf("# %-22s: %s\n", tr.T("Total Download Size"), humanSize(download))
+		fmt.Printf("# %-22s: %s\n", tr.T("Net Upgrade Size"), signedSize(installed))
+		if len(preview.foreigns) > 0 {
+			var size int64
+			for _, r := range preview.foreigns {
+				size += r.delta
+			}
+			fmt.Printf("# %-22s: %d, %s\n", tr.T("would become foreign"), len(preview.foreigns), humanSize(size))
+		}
+		if preview.ignored > 0 {
+			fmt.Printf("# %-22s: %d\n", tr.T("foreign packages ignored"), preview.ignored)
+		}
+		return nil
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(switchPreviewCmd)
+	FlagSwitchTo = branchNaneFlagType{
+		value:  "",
+		valids: configBranches(),
+	}
+	switchPreviewCmd.Flags().Var(&FlagSwitchTo, "to", tr.T("target `branch`"))
+	switchPreviewCmd.MarkFlagRequired("to")
+}
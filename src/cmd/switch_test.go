@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"mbc/alpm"
+	"testing"
+)
+
+func TestSwitchBranchReplaced(t *testing.T) {
+	locals := alpm.Packages{
+		"foo":     {NAME: "foo", VERSION: "1.0-1", SIZE: 100},
+		"old":     {NAME: "old", VERSION: "2.0-1", SIZE: 50},
+		"both":    {NAME: "both", VERSION: "1.0-1", SIZE: 30},
+		"bar":     {NAME: "bar", VERSION: "1.0-1", SIZE: 10},
+		"dropped": {NAME: "dropped", VERSION: "1.0-1", SIZE: 20},
+		"newer":   {NAME: "newer", VERSION: "3.0-1", SIZE: 40},
+	}
+	target := alpm.Packages{
+		"foo":   {NAME: "foo", VERSION: "1.1-1", ISIZE: 110, CSIZE: 40},
+		"new":   {NAME: "new", VERSION: "1.0-1", ISIZE: 60, CSIZE: 20, REPLACES: []string{"old", "newer<3.0"}, DEPENDS: []string{"lib"}},
+		"bar":   {NAME: "bar", VERSION: "1.0-1", ISIZE: 10, REPLACES: []string{"both"}},
+		"lib":   {NAME: "lib", VERSION: "1.0-1", ISIZE: 5, CSIZE: 2},
+		"other": {NAME: "other", VERSION: "1.0-1", REPLACES: []string{"dropped>=2.0"}},
+	}
+	// in another branch: not foreign
+	others := []alpm.Packages{target, {"old": locals["old"], "both": locals["both"], "dropped": locals["dropped"], "newer": locals["newer"]}}
+
+	preview := switchBranch(locals, target, others)
+
+	expected := []switchResult{
+		{"both", "1.0-1", "bar 1.0-1", -30, 0}, // replacer already installed
+		{"old", "2.0-1", "new 1.0-1", 10, 20},
+	}
+	if len(preview.replaced) != len(expected) {
+		t.Fatalf("replaced: %v, want %v", preview.replaced, expected)
+	}
+	for i, r := range preview.replaced {
+		if r != expected[i] {
+			t.Errorf("replaced: %v, want %v", r, expected[i])
+		}
+	}
+	// version of REPLACES not satisfied: foreign
+	if len(preview.foreigns) != 2 || preview.foreigns[0].name != "dropped" || preview.foreigns[1].name != "newer" {
+		t.Errorf("foreigns: %v, want dropped and newer", preview.foreigns)
+	}
+	// dependency of the replacer
+	if len(preview.news) != 1 || preview.news[0].name != "lib" {
+		t.Errorf("news: %v, want lib", preview.news)
+	}
+	if len(preview.upgrades) != 1 || preview.upgrades[0].name != "foo" {
+		t.Errorf("upgrades: %v, want foo", preview.upgrades)
+	}
+}
@@ -2,13 +2,12 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/xml"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mbc/alpm"
 	"mbc/theme"
 	"mbc/tr"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -27,54 +26,20 @@ var (
 	BuildDate string
 	GitID     string
 	Project   string
-)
-
-func getLTSFamilies() (families map[string]bool) {
 
-	type (
-		Item struct {
-			Title string `xml:"title"`
-		}
-		Channel struct {
-			Items []Item `xml:"item"`
-		}
-		RSS struct {
-			Channel Channel `xml:"channel"`
-		}
-	)
-
-	var extractFamily = func(version string) string {
-		// version "5.10.210" to "510"
-		parts := strings.SplitN(version, ".", 3)
-		if len(parts) >= 2 {
-			return parts[0] + parts[1]
-		}
-		return ""
-	}
+	FlagTreeJSON bool
+)
 
+// getLTSFamilies: longterm kernels of kernel.org, as "612"
+func getLTSFamilies(cacheDir string) (families map[string]bool) {
 	families = make(map[string]bool)
-
-	resp, err := http.Get("https://www.kernel.org/feeds/kdist.xml")
+	releases, err := kernelReleases(cacheDir)
 	if err != nil {
 		return families
 	}
-	defer resp.Body.Close()
-
-	var rss RSS
-	if err := xml.NewDecoder(resp.Body).Decode(&rss); err != nil {
-		return families
-	}
-
-	for _, item := range rss.Channel.Items {
-		if strings.Contains(item.Title, "longterm") {
-			fields := strings.Fields(item.Title)
-			if len(fields) > 1 {
-				version := fields[0]
-				family := extractFamily(version)
-				if family != "" {
-					families[family] = true
-				}
-			}
+	for family, release := range releases {
+		if release.Moniker == "longterm" && !release.EOL {
+			families[family] = true
 		}
 	}
 	return
@@ -217,13 +182,113 @@ func toHomeDir(abspath string) string {
 	return strings.Replace(abspath, homeDir, "~", 1)
 }
 
+// statistics of a repository, for `tree --json`
+type treeRepo struct {
+	Branch   string      `json:"branch"`
+	Repo     string      `json:"repo"`
+	Date     time.Time   `json:"date"` // database modification time
+	Packages int         `json:"packages"`
+	Csize    int64       `json:"csize"` // compressed, download size
+	Isize    int64       `json:"isize"` // installed size
+	Newer    int         `json:"newer"` // than archlinux
+	Equal    int         `json:"equal"`
+	Older    int         `json:"older"`
+	Only     int         `json:"only"`             // not in archlinux
+	Growth   *treeGrowth `json:"growth,omitempty"` // since the previous database
+}
+
+type treeGrowth struct {
+	Date     time.Time `json:"date"` // previous database
+	Packages int       `json:"packages"`
+	Csize    int64     `json:"csize"`
+	Isize    int64     `json:"isize"`
+}
+
+func sumSizes(pkgs alpm.Packages) (csize, isize int64) {
+	for _, pkg := range pkgs {
+		csize += pkg.CSIZE
+		isize += pkg.ISIZE
+	}
+	return
+}
+
+// repoStats of BRANCH/sync/REPO.db, compared to archlinux and BRANCH/previous/REPO.db
+func repoStats(cacheDir, branch, repo string, archs alpm.Packages) (stats treeRepo, pkgs alpm.Packages) {
+	dbFile := filepath.Join(cacheDir, branch, "sync", repo+".db")
+	stats = treeRepo{Branch: branch, Repo: repo}
+	if info, err := os.Stat(dbFile); err == nil {
+		stats.Date = info.ModTime()
+	}
+	pkgs, _ = alpm.Load(filepath.Dir(dbFile), []string{repo}, branch, false)
+	stats.Packages = len(pkgs)
+	stats.Csize, stats.Isize = sumSizes(pkgs)
+
+	if branch != "archlinux" && len(archs) > 0 {
+		for name, pkg := range pkgs {
+			arch, ok := archs[name]
+			if !ok {
+				stats.Only++
+				continue
+			}
+			switch alpm.AlpmPkgVerCmp(pkg.VERSION, arch.VERSION) {
+			case 1:
+				stats.Newer++
+			case 0:
+				stats.Equal++
+			default:
+				stats.Older++
+			}
+		}
+	}
+
+	previousDir := _getPreviousDir(cacheDir, branch)
+	if info, err := os.Stat(filepath.Join(previousDir, repo+".db")); err == nil && info.Size() > 0 {
+		previous, _ := alpm.Load(previousDir, []string{repo}, branch, false)
+		csize, isize := sumSizes(previous)
+		stats.Growth = &treeGrowth{
+			Date:     info.ModTime(),
+			Packages: stats.Packages - len(previous),
+			Csize:    stats.Csize - csize,
+			Isize:    stats.Isize - isize,
+		}
+	}
+	return stats, pkgs
+}
+
+// treeJSON: statistics of all repositories, on one line to append to a file
+func treeJSON(config Config, cacheDir string) error {
+	var archs alpm.Packages
+	if _, err := os.Stat(filepath.Join(cacheDir, "archlinux", "sync")); err == nil {
+		archs, _ = alpm.Load(filepath.Join(cacheDir, "archlinux", "sync"), config.Repos, "archlinux", false)
+	}
+	result := struct {
+		Date  time.Time  `json:"date"`
+		Repos []treeRepo `json:"repos"`
+	}{Date: time.Now(), Repos: []treeRepo{}}
+	for _, branch := range append(config.Branches, "archlinux") {
+		for _, repo := range config.Repos {
+			if info, err := os.Stat(filepath.Join(cacheDir, branch, "sync", repo+".db")); err != nil || info.Size() < 1 {
+				continue
+			}
+			stats, _ := repoStats(cacheDir, branch, repo, archs)
+			result.Repos = append(result.Repos, stats)
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
 func tree(config Config, cacheDir, confFilename string) {
 
 	var ltsFamilies map[string]bool
 	ltsChan := make(chan struct{})
 	go func() {
 		defer close(ltsChan)
-		ltsFamilies = getLTSFamilies()
+		ltsFamilies = getLTSFamilies(cacheDir)
 	}()
 
 	kernels := []string{}
@@ -237,6 +302,11 @@ func tree(config Config, cacheDir, confFilename string) {
 		}
 	}
 
+	var archs alpm.Packages
+	if _, err := os.Stat(filepath.Join(cacheDir, "archlinux", "sync")); err == nil {
+		archs, _ = alpm.Load(filepath.Join(cacheDir, "archlinux", "sync"), config.Repos, "archlinux", false)
+	}
+
 	for _, branch := range branches {
 		fmt.Println(theme.Theme(branch) + branch + theme.Theme(""))
 		keys := []string{}
@@ -247,7 +317,6 @@ func tree(config Config, cacheDir, confFilename string) {
 					fmt.Println("Error creating directory:", err)
 					continue
 				}
-				filepath.Join(dirPath, repo+".db")
 
 				fileInfo, _ := os.Stat(filepath.Join(dirPath, repo+".db"))
 				if fileInfo.Size() < 1 {
@@ -260,12 +329,25 @@ func tree(config Config, cacheDir, confFilename string) {
 					days = fmt.Sprintf("(%d %s)", int(d.Hours()/24), tr.T("days"))
 				}
 
-				pkgs, _ := alpm.Load(dirPath, []string{repo}, branch, false)
+				stats, pkgs := repoStats(cacheDir, branch, repo, archs)
 				sep := theme.Theme(branch) + "-" + theme.Theme("")
-				fmt.Printf("  %s %-*s   %6d    (%s)  %s\n", sep, padw, repo, len(pkgs), tf.Format("2006-01-02 15:04"), days)
+				fmt.Printf("  %s %-*s   %6d  %11s %11s    (%s)  %s\n", sep, padw, repo, stats.Packages,
+					humanSize(stats.Csize), humanSize(stats.Isize), tf.Format("2006-01-02 15:04"), days)
+				details := []string{}
+				if branch != "archlinux" && len(archs) > 0 {
+					details = append(details, fmt.Sprintf("%s %d, %s %d, %s %d, %s %d", tr.T("newer"), stats.Newer,
+						tr.T("equal"), stats.Equal, tr.T("older"), stats.Older, tr.T("Manjaro only"), stats.Only))
+				}
+				if stats.Growth != nil {
+					details = append(details, fmt.Sprintf("%s: %+d %s, %s", tr.T("since last update"), stats.Growth.Packages,
+						tr.T("packages"), signedSize(stats.Growth.Csize)))
+				}
+				if len(details) > 0 {
+					fmt.Printf("    %*s %s%s%s\n", padw, "", theme.ColorGray, strings.Join(details, "   "), theme.ColorNone)
+				}
 				if repo == "core" && len(pkgs) > 1 {
 					// search kernels
-					keys = getKeys(map[string]alpm.Packages{"core": pkgs}, `^linux\d{2,3}(-rt)?$`)
+					keys = getKeys(map[string]alpm.Packages{"core": pkgs}, alpm.KernelPattern)
 				}
 			}
 		}
@@ -295,11 +377,21 @@ func tree(config Config, cacheDir, confFilename string) {
 		}
 	}
 	fmt.Printf("# %-16s: %s\n", tr.T("mirrors"), strings.Join(urls, ", "))
+	if mirror, branch, err := alpm.DetectMirror(config.Branches); err == nil {
+		if branch == "" {
+			branch = systemBranch(config.Branches)
+		}
+		fmt.Printf("# %-16s: %s  %s\n", tr.T("system branch"), theme.Theme(branch)+branch+theme.ColorNone, theme.ColorGray+mirror+theme.ColorNone)
+	} else if branch, err := alpm.DetectBranch(); err == nil {
+		fmt.Printf("# %-16s: %s\n", tr.T("system branch"), theme.Theme(branch)+branch+theme.ColorNone)
+	}
 	fmt.Printf("# %-16s: %s\n", tr.T("database"), toHomeDir(cacheDir))
 	fmt.Printf("# %-16s: %s\n", tr.T("config"), toHomeDir(confFilename))
 	if alpm.LocalDBExists() {
 		if pkgs, err := alpm.LoadLocal(); err == nil {
-			fmt.Printf("# %-16s: %d %s\n", tr.T("installed"), len(pkgs), tr.T("packages"))
+			fmt.Printf("# %-16s: %d %s (%s %d, %s %d, %s %d)\n", tr.T("installed"), len(pkgs), tr.T("packages"),
+				tr.T("explicit"), len(pkgs.Explicit()), tr.T("dependencies"), len(pkgs.Dependencies()),
+				tr.T("orphans"), len(pkgs.Orphans()))
 		}
 	}
 	fmt.Printf("# %s: V%v %v %v %v\n", filepath.Base(os.Args[0]), Version, GitID, GitBranch, BuildDate)
@@ -307,18 +399,29 @@ func tree(config Config, cacheDir, confFilename string) {
 
 var treeCmd = &cobra.Command{
 	Use:   "tree",
-	Short: "infos on local repos",
-	Long:  ``,
-	Run: func(cmd *cobra.Command, args []string) {
+	Short: tr.T("infos on local repos"),
+	Long: `Repositories of each branch: packages, download and installed sizes,
+packages newer, equal or older than archlinux, or only in Manjaro,
+growth since the previous database (before the last update)
+ex:
+	tree
+	tree --json >> ~/mbc-stats.json
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 		cacheDir := ctx.Value(ctxCacheDir).(string)
 		confFilename := ctx.Value(ctxConfFilename).(string)
+		if FlagTreeJSON {
+			return treeJSON(ctx.Value(ctxConfigVars).(Config), cacheDir)
+		}
 		tree(ctx.Value(ctxConfigVars).(Config), cacheDir, confFilename)
+		return nil
 	},
 }
 
 func init() {
-	treeCmd.Short = tr.T(treeCmd.Short)
 	rootCmd.AddCommand(treeCmd)
+	treeCmd.Flags().BoolVarP(&FlagTreeJSON, "json", "", FlagTreeJSON, tr.T("statistics of repositories in json"))
+	treeCmd.Flags().StringVarP(&FlagReleasesFile, "releases-file", "", "", tr.T("kernel.org releases.json or kdist.xml `file`"))
 	setCompletion()
 }
@@ -15,12 +15,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func _getDateFile() string {
-	return filepath.Join(Config{}.cache(), "date")
+func _getDateFile(cacheDir string) string {
+	return filepath.Join(cacheDir, "date")
 }
 
-func updateDateFromFile() int {
-	data, err := os.ReadFile(_getDateFile())
+func updateDateFromFile(cacheDir string) int {
+	data, err := os.ReadFile(_getDateFile(cacheDir))
 	if err != nil {
 		return 0
 	}
@@ -31,15 +31,17 @@ func updateDateFromFile() int {
 	return 0
 }
 
-func updateDateToFile() error {
-	file, err := os.Create(_getDateFile())
-	if err != nil {
-		return err
+// autoUpdate the databases older than AutoUpdate days, before a command reads them
+func autoUpdate(cmd *cobra.Command, cacheDir string) {
+	if updateDateFromFile(cacheDir) >= AutoUpdate {
+		updateCmd.Run(cmd, []string{""})
+		fmt.Println()
 	}
-	defer file.Close()
+}
 
-	_, err = file.WriteString(time.Now().Format(time.RFC3339))
-	return err
+// updateDateToFile: a reader never sees an empty date, it would update again
+func updateDateToFile(cacheDir string) error {
+	return writeFile(_getDateFile(cacheDir), []byte(time.Now().Format(time.RFC3339)))
 }
 
 func shouldDownload(url, filePath string) (bool, error) {
@@ -50,7 +52,7 @@ func shouldDownload(url, filePath string) (bool, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf(tr.T("failed to access remote file: %s"), resp.Status)
+		return false, fmt.Errorf("%s: %s", tr.T("failed to access remote file"), resp.Status)
 	}
 
 	fileInfo, err := os.Stat(filePath)
@@ -92,38 +94,94 @@ func downloadFile(url, filepath string) error {
 		return fmt.Errorf("%s: %s", tr.T("download failed"), resp.Status)
 	}
 
-	out, err := os.Create(filepath)
+	// readers never see a partial database
+	out, err := os.Create(filepath + ".part")
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	defer os.Remove(filepath + ".part")
 
 	_, err = io.Copy(out, resp.Body)
-	return err
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	keepPrevious(filepath)
+	return os.Rename(filepath+".part", filepath)
 }
 
-func createConfigPacman(directory string, repos []string) error {
-	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
-		fmt.Printf("%s: %v", tr.T("Error creating directory"), err)
+// writeFile as os.WriteFile, but readers and other writers never see a partial file:
+// data is written in a temporary file of the same directory, then renamed
+func writeFile(filename string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
 		return err
 	}
-	f, err := os.Create(directory + "/pacman.conf")
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.part")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	content := "[options]\nDBPath = " + directory + "\nColor\n\n"
-	for _, repo := range repos {
-		content = content + "[" + repo + "]\n"
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
 	}
-	f.WriteString(content)
-	return nil
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// _getPreviousDir: databases before the last update, as BRANCH/previous/
+func _getPreviousDir(cacheDir, branch string) string {
+	return filepath.Join(cacheDir, branch, "previous")
+}
+
+// keepPrevious moves BRANCH/sync/REPO.db to BRANCH/previous/REPO.db
+func keepPrevious(path string) {
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	dir := filepath.Join(filepath.Dir(filepath.Dir(path)), "previous")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return
+	}
+	os.Rename(path, filepath.Join(dir, filepath.Base(path)))
 }
 
 func update(config Config, silent bool) {
 
 	cacheBase := config.cache()
 
+	if cacheLocker != nil {
+		// auto update by a reader: shared lock becomes exclusive while downloading
+		defer func() {
+			if err := cacheLocker.relock(false); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", tr.T("Error locking cache"), err)
+			}
+		}()
+		if err := cacheLocker.relock(true); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", tr.T("Error locking cache"), err)
+			return
+		}
+		if updateDateFromFile(cacheBase) < AutoUpdate {
+			// updated by another reader while waiting for the lock
+			return
+		}
+	} else {
+		lock, err := lockCache(cacheBase, true, FlagLockTimeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", tr.T("Error locking cache"), err)
+			return
+		}
+		defer lock.unlock()
+	}
+
 	var wg sync.WaitGroup
 
 	var out io.Writer = os.Stdout
@@ -135,10 +193,6 @@ func update(config Config, silent bool) {
 	for _, url := range config.Urls {
 		if strings.Contains(url, "$branch") {
 			for _, branch = range config.Branches {
-				err := createConfigPacman(filepath.Join(cacheBase, branch), config.Repos)
-				if err != nil {
-					panic(err)
-				}
 				for _, repo := range config.Repos {
 					for _, arch := range config.Arch {
 						finalURL := strings.ReplaceAll(url, "$branch", branch)
@@ -178,10 +232,6 @@ func update(config Config, silent bool) {
 			}
 		} else {
 			branch = "archlinux"
-			err := createConfigPacman(filepath.Join(cacheBase, branch), config.Repos)
-			if err != nil {
-				panic(err)
-			}
 			for _, repo := range config.Repos {
 				for _, arch := range config.Arch {
 					//finalURL := strings.ReplaceAll(url, "$branch", branch)
@@ -226,15 +276,17 @@ func update(config Config, silent bool) {
 		fmt.Println("\n## End auto update")
 	}
 
-	updateDateToFile()
+	if err := updateDateToFile(cacheBase); err != nil {
+		fmt.Fprintln(os.Stderr, "WARNING!", err)
+	}
 }
 
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
 	Use:     "update",
 	Aliases: []string{"upgrade", "up"},
-	Short:   "Update branches",
-	Long:    `Update Manjaro and Archlinux pacman databases`,
+	Short:   tr.T("Update branches"),
+	Long:    tr.T(`Update Manjaro and Archlinux pacman databases`),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
 		silent := len(args) > 0 && args[0] == "silent"
@@ -243,7 +295,5 @@ var updateCmd = &cobra.Command{
 }
 
 func init() {
-	updateCmd.Short = tr.T(updateCmd.Short)
-	updateCmd.Long = tr.T(updateCmd.Long)
 	rootCmd.AddCommand(updateCmd)
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"mbc/alpm"
+	"mbc/tr"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var FlagVercmpDep string
+
+// vercmp returns the output and the exit code of the command, 2 for a usage error
+func vercmp(args []string, dep string) (string, int) {
+	if dep != "" {
+		if len(args) != 1 {
+			return "", 2
+		}
+		if !alpm.Satisfies(args[0], dep) {
+			return "", 1
+		}
+		return "", 0
+	}
+	switch len(args) {
+	case 0:
+		return "", 2
+	case 1:
+		// as vercmp, compare to an empty version
+		args = append(args, "")
+	}
+	return strconv.Itoa(alpm.AlpmPkgVerCmp(args[0], args[1])), 0
+}
+
+// vercmpCmd as pacman `vercmp`, without pacman
+var vercmpCmd = &cobra.Command{
+	Use:   "vercmp <version1> <version2>",
+	Short: tr.T("compare package versions"),
+	Long: `Compare package versions as pacman vercmp, pacman is not required
+output:
+  < 0 : if version1 < version2
+    0 : if version1 == version2
+  > 0 : if version1 > version2
+with --dep, exit code is 0 if version satisfies the dependency, else 1
+ex:
+	vercmp 1:1.0-1 1.1-2
+	vercmp --dep "glibc>=2.40" 2.41+r9+g9a9d3cd0d2e4-1
+`,
+	// no configuration and no cache: usable in scripts
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		output, code := vercmp(args, FlagVercmpDep)
+		if code == 2 {
+			cmd.Usage()
+		}
+		if output != "" {
+			fmt.Println(output)
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+	},
+	Args: cobra.MaximumNArgs(2),
+}
+
+func init() {
+	rootCmd.AddCommand(vercmpCmd)
+	vercmpCmd.Flags().StringVarP(&FlagVercmpDep, "dep", "d", "", tr.T("exit 0 if version satisfies this `dependency`"))
+}
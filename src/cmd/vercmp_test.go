@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// the table of alpm tests: "version1 version2 expected" by line
+func readVercmpTests(t *testing.T) [][3]string {
+	t.Helper()
+	f, err := os.Open("../alpm/testdata/vercmp.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := [][3]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && !strings.HasPrefix(fields[0], "#") {
+			tests = append(tests, [3]string{fields[0], fields[1], fields[2]})
+		}
+	}
+	if len(tests) < 1 {
+		t.Fatal("no test in ../alpm/testdata/vercmp.txt")
+	}
+	return tests
+}
+
+func TestVercmp(t *testing.T) {
+	for _, test := range readVercmpTests(t) {
+		if output, code := vercmp([]string{test[0], test[1]}, ""); output != test[2] || code != 0 {
+			t.Errorf("vercmp %s %s = %q exit %d, want %q exit 0", test[0], test[1], output, code, test[2])
+		}
+	}
+}
+
+// exit code of --dep, with the operators matching the expected comparison
+func TestVercmpDep(t *testing.T) {
+	operators := map[string][]string{
+		"-1": {"<", "<="},
+		"0":  {"<=", "=", ">="},
+		"1":  {">", ">="},
+	}
+	for _, test := range readVercmpTests(t) {
+		for _, mod := range []string{"<", "<=", "=", ">=", ">"} {
+			want := 1
+			for _, valid := range operators[test[2]] {
+				if mod == valid {
+					want = 0
+				}
+			}
+			dep := "foo" + mod + test[1]
+			if _, code := vercmp([]string{test[0]}, dep); code != want {
+				t.Errorf("vercmp --dep %q %s: exit %d, want %d", dep, test[0], code, want)
+			}
+		}
+	}
+}
+
+func TestVercmpUsage(t *testing.T) {
+	tests := []struct {
+		args   []string
+		dep    string
+		output string
+		code   int
+	}{
+		{[]string{}, "", "", 2},
+		{[]string{"1.0"}, "", strconv.Itoa(1), 0},
+		{[]string{}, "foo>=1", "", 2},
+		{[]string{"1.0", "2.0"}, "foo>=1", "", 2},
+	}
+	for _, test := range tests {
+		if output, code := vercmp(test.args, test.dep); output != test.output || code != test.code {
+			t.Errorf("vercmp %v --dep %q = %q exit %d, want %q exit %d", test.args, test.dep, output, code, test.output, test.code)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,12 +21,53 @@ var (
 	FlagLocal     bool
 	FlagKernel    bool
 	FlagGrep      string
+	FlagOnly      []string
+	FlagHide      []string
 )
 
+// "rebuilds" in --only and --hide: pkgrel bumps and same versions rebuilt
+var changeGroups = map[string][]string{
+	"rebuilds": {alpm.ChangePkgrel, alpm.ChangeRebuild},
+}
+
 type versionResult struct {
 	name    string
 	vfirst  string
 	vsecond string
+	repo    string
+	local   string
+	change  string
+}
+
+// expand groups and check kinds of change of --only and --hide
+func parseChanges(values []string) (changes map[string]bool, err error) {
+	changes = make(map[string]bool)
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if group, ok := changeGroups[value]; ok {
+			for _, change := range group {
+				changes[change] = true
+			}
+			continue
+		}
+		if !slices.Contains(alpm.Changes, value) {
+			return nil, fmt.Errorf("%s: %s (%s)", tr.T("invalid change"), value, strings.Join(append(alpm.Changes, "rebuilds"), ", "))
+		}
+		changes[value] = true
+	}
+	return changes, nil
+}
+
+// wantChange filters on kind of change with --only and --hide
+// same versions rebuilt are only displayed if asked with --only
+func wantChange(change string, only, hide map[string]bool) bool {
+	if hide[change] {
+		return false
+	}
+	if len(only) > 0 {
+		return only[change]
+	}
+	return change != alpm.ChangeRebuild
 }
 
 // Regex pour supprimer les codes ANSI
@@ -68,25 +110,31 @@ func version(versions *[]versionResult, config Config, cacheDir string, branches
 	tmp[0], _ = alpm.Load(filepath.Join(cacheDir, branches[0], "sync"), config.Repos, branches[0], false)
 	tmp[1], _ = alpm.Load(filepath.Join(cacheDir, branches[1], "sync"), config.Repos, branches[1], false)
 
+	locals := alpm.Packages{}
+	if FlagLocal || FlagInstalled {
+		var err error
+		if locals, err = alpm.LoadLocal(); err != nil {
+			fmt.Fprintln(os.Stderr, "WARNING!", err)
+		}
+	}
 	if FlagLocal {
 		// in output, whant only installed package
-		if locals, err := alpm.LoadLocal(); err == nil {
+		if len(locals) > 0 {
 			tmp[0] = alpm.FilterOnly(tmp[0], locals)
 			tmp[1] = alpm.FilterOnly(tmp[1], locals)
 		}
 	}
 
+	// errors are checked in Args
+	only, _ := parseChanges(FlagOnly)
+	hide, _ := parseChanges(FlagHide)
+	changes := make(map[string]string)
 	for key := range tmp[0] {
 		if _, exists := tmp[1][key]; exists {
-			if tmp[0][key].VERSION != tmp[1][key].VERSION {
-				tmpkeys[key] = true
-			}
-		}
-	}
-	for key := range tmp[1] {
-		if _, exists := tmp[0][key]; exists {
-			if tmp[0][key].VERSION != tmp[1][key].VERSION {
+			change := alpm.ClassifyPackages(tmp[0][key], tmp[1][key])
+			if change != alpm.ChangeNone && wantChange(change, only, hide) {
 				tmpkeys[key] = true
+				changes[key] = change
 			}
 		}
 	}
@@ -94,7 +142,7 @@ func version(versions *[]versionResult, config Config, cacheDir string, branches
 	keys := make([]string, 0, len(tmpkeys))
 	FlagGrep = strings.ToLower(FlagGrep)
 	if len(FlagGrep) > 6 && FlagGrep[0:7] == "#kernel" {
-		FlagGrep = `^linux\d{2,3}(-rt)?$`
+		FlagGrep = alpm.KernelPattern
 	}
 	reg, err := regexp.Compile(FlagGrep)
 	if err != nil {
@@ -135,7 +183,16 @@ func version(versions *[]versionResult, config Config, cacheDir string, branches
 			highlightVa = highlightDiff(vb, va, theme.Theme(branches[0]))
 		}
 		if highlightVb != "" {
-			*versions = append(*versions, versionResult{pkg, highlightVa, highlightVb})
+			repo := tmp[0][pkg].REPO
+			if tmp[1][pkg].REPO != repo {
+				// moved, as "core>extra"
+				repo = theme.Theme(branches[0]) + repo + theme.ColorNone + ">" + theme.Theme(branches[1]) + tmp[1][pkg].REPO + theme.ColorNone
+			}
+			local := ""
+			if l, ok := locals[pkg]; ok && FlagInstalled {
+				local = l.VERSION + " " + localMark(l.VERSION, va) + localMark(l.VERSION, vb)
+			}
+			*versions = append(*versions, versionResult{pkg, highlightVa, highlightVb, repo, local, changes[pkg]})
 			if len(pkg) > col1 {
 				col1 = len(pkg)
 			}
@@ -150,17 +207,20 @@ func version(versions *[]versionResult, config Config, cacheDir string, branches
 // diffCmd represents the diff command
 var versionCmd = &cobra.Command{
 	Use:   "version",
-	Short: "compare versions over branches",
+	Short: tr.T("compare versions over branches"),
 	Long: `Example:
   mbc version --grep '#kernel' -st    # kernels stable / testing
+  mbc version -st --local -i          # installed version: "<" behind, "=" same, ">" ahead of branch
+  mbc version -st --hide rebuilds     # without pkgrel bumps and rebuilds
+  mbc version -st --only major,minor  # "rebuild": same version, other build date
 
   mbc compare "stable" vs "unstable":
 version  -su --grep '^linux(..|...)$'
-# package                          / stable                     / unstable
-linux612                             6.12.19-1                    6.12.20-2
-linux613                             6.13.7-1                     6.13.8-2
-linux614                             6.14.0rc7-1                  6.14.0-1
-linux66                              6.6.83-1                     6.6.84-1
+# package       repository   / stable                     / unstable
+linux612        core           6.12.19-1                    6.12.20-2
+linux613        core           6.13.7-1                     6.13.8-2
+linux614        core           6.14.0rc7-1                  6.14.0-1
+linux66         core           6.6.83-1                     6.6.84-1
 ...
 `,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -169,10 +229,7 @@ linux66                              6.6.83-1                     6.6.84-1
 		cacheDir := ctx.Value(ctxCacheDir).(string)
 		branches = FlagBranches.toSlice()
 
-		if updateDateFromFile() >= AutoUpdate {
-			updateCmd.Run(cmd, []string{""})
-			fmt.Println()
-		}
+		autoUpdate(cmd, cacheDir)
 
 		if FlagKernel {
 			FlagGrep = "#kernel"
@@ -181,32 +238,45 @@ linux66                              6.6.83-1                     6.6.84-1
 		var versions []versionResult
 		col1, col2, grepflag := version(&versions, conf, cacheDir, branches)
 
-		fmt.Printf("# %-"+strconv.Itoa(col1-2)+"s %-"+strconv.Itoa(col2+9)+"s / %s\n", tr.T("compare versions"), theme.Theme(branches[0])+branches[0]+theme.Theme(""), theme.Theme(branches[1])+branches[1]+theme.Theme(""))
+		second := theme.Theme(branches[1]) + branches[1] + theme.Theme("")
+		if FlagInstalled {
+			second = padRightANSI(second, col2) + " " + tr.T("installed")
+		}
+		fmt.Printf("# %-"+strconv.Itoa(col1-2)+"s %-12s %-11s %-"+strconv.Itoa(col2+9)+"s / %s\n", tr.T("compare versions"), tr.T("repository"), tr.T("change"), theme.Theme(branches[0])+branches[0]+theme.Theme(""), second)
 		for _, v := range versions {
 			v.vfirst = padRightANSI(v.vfirst, col2)
-			fmt.Printf("%-"+strconv.Itoa(col1)+"s %-"+strconv.Itoa(col2)+"s %s\n", v.name, v.vfirst, v.vsecond)
+			if FlagInstalled {
+				v.vsecond = padRightANSI(v.vsecond, col2) + " " + v.local
+			}
+			fmt.Printf("%-"+strconv.Itoa(col1)+"s %s %s %-"+strconv.Itoa(col2)+"s %s\n", v.name, padRightANSI(v.repo, 12), theme.ColorGray+fmt.Sprintf("%-11s", v.change)+theme.ColorNone, v.vfirst, v.vsecond)
 		}
 		fmt.Println()
 		fmt.Printf("# %d %s\n", len(versions), tr.T("packages"))
 		if grepflag != "" {
 			fmt.Printf("# %s: %v\n", tr.T("filter"), grepflag)
 		}
+		if len(FlagOnly) > 0 {
+			fmt.Printf("# %s: %s\n", tr.T("only"), strings.Join(FlagOnly, ","))
+		}
+		if len(FlagHide) > 0 {
+			fmt.Printf("# %s: %s\n", tr.T("hidden"), strings.Join(FlagHide, ","))
+		}
 	},
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) > 0 {
-			return fmt.Errorf(tr.T("use only flags! %v too mutch"), args)
+		if err := onlyFlags(cmd, args); err != nil {
+			return err
 		}
-		result := FlagBranches.count()
-		if result != 2 {
-			return fmt.Errorf(tr.T("invalid branches specified: %s"), "not 2")
+		for _, values := range [][]string{FlagOnly, FlagHide} {
+			if _, err := parseChanges(values); err != nil {
+				return err
+			}
 		}
-		return nil
+		return twoBranches()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
-	versionCmd.Short = tr.T(versionCmd.Short)
 	versionCmd.Long = versionCmd.Short + "\n\n" + versionCmd.Long
 	versionCmd.Flags().BoolVarP(&FlagBranches.FlagStable, "stable", "s", FlagBranches.FlagStable, "stable "+tr.T("branch"))
 	versionCmd.Flags().BoolVarP(&FlagBranches.FlagTesting, "testing", "t", FlagBranches.FlagTesting, "testing "+tr.T("branch"))
@@ -215,7 +285,8 @@ func init() {
 	versionCmd.Flags().BoolVarP(&FlagDowngrade, "overgrade", "", FlagDowngrade, tr.T("display only downgrade up"))
 	versionCmd.Flags().StringVarP(&FlagGrep, "grep", "", "", tr.T("name filter (regex)"))
 	versionCmd.Flags().BoolVarP(&FlagKernel, "kernel", "k", FlagKernel, "--grep '#kernel'")
-	if alpm.LocalDBExists() {
-		versionCmd.Flags().BoolVarP(&FlagLocal, "local", "", FlagInstalled, tr.T("only installed packages filter"))
-	}
+	versionCmd.Flags().BoolVarP(&FlagLocal, "local", "", FlagInstalled, tr.T("only installed packages filter"))
+	versionCmd.Flags().BoolVarP(&FlagInstalled, "installed", "i", FlagInstalled, tr.T("installed version"))
+	versionCmd.Flags().StringSliceVar(&FlagOnly, "only", nil, tr.T("only these changes")+": "+strings.Join(alpm.Changes, ", ")+", rebuilds")
+	versionCmd.Flags().StringSliceVar(&FlagHide, "hide", nil, tr.T("hide these changes (as --only)"))
 }
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mbc/alpm"
+	"mbc/theme"
+	"mbc/tr"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	FlagWatchNotify []string
+	FlagWatchNoSave bool
+)
+
+var watchSinks = []string{"stdout", "notify-send", "command"}
+
+// watchState: versions of the watched packages, by branch and name
+type watchState map[string]map[string]string
+
+// change of a watched package, sent in json to the command
+type watchChange struct {
+	Branch   string `json:"branch"`
+	Name     string `json:"name"`
+	Old      string `json:"old"` // empty: new in branch
+	New      string `json:"new"` // empty: removed from branch
+	Change   string `json:"change,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	Packager string `json:"packager,omitempty"`
+}
+
+// _getWatchFile: state of the last run, in the databases directory
+func _getWatchFile(cacheDir string) string {
+	return filepath.Join(cacheDir, "watch.json")
+}
+
+func loadWatchState(filename string) (watchState, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var state watchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return state, nil
+}
+
+func saveWatchState(filename string, state watchState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, data)
+}
+
+// loadError: a database not read, its packages would be reported as removed
+func loadError(pkgs alpm.Packages, warnings []string) error {
+	for _, warning := range warnings {
+		// "# ignore duplicate" is not an error
+		if !strings.HasPrefix(warning, "# ") {
+			return errors.New(strings.TrimSpace(warning))
+		}
+	}
+	if len(pkgs) < 1 {
+		return errors.New(tr.T("no package"))
+	}
+	return nil
+}
+
+// watchChanges between the last state and the packages of branches
+func watchChanges(previous watchState, pkgs map[string]alpm.Packages, branches []string) (changes []watchChange) {
+	for _, branch := range branches {
+		old, ok := previous[branch]
+		if !ok {
+			// branch not watched at the last run
+			continue
+		}
+		for name, version := range old {
+			if _, ok := pkgs[branch][name]; !ok {
+				changes = append(changes, watchChange{Branch: branch, Name: name, Old: version})
+			}
+		}
+		for name, pkg := range pkgs[branch] {
+			if old[name] == pkg.VERSION {
+				continue
+			}
+			change := watchChange{Branch: branch, Name: name, Old: old[name], New: pkg.VERSION, Repo: pkg.REPO, Packager: pkg.PACKAGER}
+			if change.Old != "" {
+				change.Change = alpm.ClassifyChange(change.Old, change.New)
+			}
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return slices.Index(branches, changes[i].Branch) < slices.Index(branches, changes[j].Branch)
+	})
+	return changes
+}
+
+func (c watchChange) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s %s %s", c.Name, c.New, tr.T("new in")+" "+c.Branch)
+	case c.New == "":
+		return fmt.Sprintf("%s %s %s", c.Name, c.Old, tr.T("removed from")+" "+c.Branch)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", c.Name, c.Branch, c.Old, c.New)
+}
+
+// notify the changes to a sink: stdout, notify-send or command
+func notify(sink string, changes []watchChange, command string) error {
+	switch sink {
+	case "stdout":
+		for _, c := range changes {
+			old, new := c.Old, c.New
+			if old == "" {
+				old = "-"
+			}
+			if new == "" {
+				new = "-"
+			}
+			fmt.Printf("%-30s %s %-24s %s %s\n", c.Name, padRightANSI(theme.Theme(c.Branch)+c.Branch+theme.ColorNone, 10),
+				old, padRightANSI(theme.Theme(c.Branch)+new+theme.ColorNone, 24), theme.ColorGray+c.Change+theme.ColorNone)
+		}
+	case "notify-send":
+		lines := make([]string, 0, len(changes))
+		for _, c := range changes {
+			lines = append(lines, c.String())
+		}
+		title := fmt.Sprintf("mbc: %d %s", len(changes), tr.T("changes"))
+		return exec.Command("notify-send", "--app-name=mbc", title, strings.Join(lines, "\n")).Run()
+	case "command":
+		if command == "" {
+			return errors.New(tr.T("no command in watch configuration"))
+		}
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdin = bytes.NewReader(data)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		return cmd.Run()
+	}
+	return nil
+}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: tr.T("version changes of watched packages"),
+	Long: `Version changes, since the last run, of packages in the watch
+section of configuration, in all branches:
+  watch:
+    packages: ["mesa", "pamac-.*", "linux\\d+"]
+    notify: ["stdout", "notify-send", "command"]
+    command: "my-script"   # changes in json on stdin
+
+The first run only saves the versions, in the databases directory
+ex:
+	watch
+	watch --notify notify-send
+	watch --no-save
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		conf := ctx.Value(ctxConfigVars).(Config)
+		cacheDir := ctx.Value(ctxCacheDir).(string)
+
+		if len(conf.Watch.Packages) < 1 {
+			return errors.New(tr.T("no package to watch, see `watch:` in configuration"))
+		}
+		sinks := FlagWatchNotify
+		if len(sinks) < 1 {
+			sinks = conf.Watch.Notify
+		}
+		if len(sinks) < 1 {
+			sinks = []string{"stdout"}
+		}
+		for _, sink := range sinks {
+			if !slices.Contains(watchSinks, sink) {
+				return fmt.Errorf("%s: %s (%s)", tr.T("invalid notify"), sink, strings.Join(watchSinks, ", "))
+			}
+		}
+
+		autoUpdate(cmd, cacheDir)
+
+		// two runs would notify the same changes: one at a time reads, notifies and saves the state
+		if err := cacheLocker.relock(true); err != nil {
+			return err
+		}
+		filename := _getWatchFile(cacheDir)
+		previous, err := loadWatchState(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		branches := append(slices.Clone(conf.Branches), "archlinux")
+		pkgs := make(map[string]alpm.Packages, len(branches))
+		current := make(watchState, len(branches))
+		loaded := make([]string, 0, len(branches))
+		for _, branch := range branches {
+			all, warnings := alpm.Load(filepath.Join(cacheDir, branch, "sync"), conf.Repos, branch, false)
+			if err := loadError(all, warnings); err != nil {
+				// versions of the last run kept, the changes are reported next time
+				fmt.Fprintln(os.Stderr, "WARNING!", branch+":", err, "-", tr.T("branch not watched"))
+				if old, ok := previous[branch]; ok {
+					current[branch] = old
+				}
+				continue
+			}
+			loaded = append(loaded, branch)
+			pkgs[branch] = make(alpm.Packages)
+			current[branch] = make(map[string]string)
+			for name, pkg := range all {
+				if conf.Watch.Match(name) {
+					pkgs[branch][name] = pkg
+					current[branch][name] = pkg.VERSION
+				}
+			}
+		}
+
+		changes := watchChanges(previous, pkgs, loaded)
+
+		if previous == nil {
+			fmt.Printf("# %s\n", tr.T("first run, versions saved"))
+		}
+		failed := false
+		for _, sink := range sinks {
+			if len(changes) < 1 && sink != "stdout" {
+				continue
+			}
+			if err := notify(sink, changes, conf.Watch.Command); err != nil {
+				fmt.Fprintln(os.Stderr, "WARNING!", sink+":", err)
+				failed = true
+			}
+		}
+		watched := map[string]bool{}
+		for _, branch := range branches {
+			for name := range current[branch] {
+				watched[name] = true
+			}
+		}
+		if slices.Contains(sinks, "stdout") {
+			fmt.Printf("# %d %s, %d %s\n", len(changes), tr.T("changes"), len(watched), tr.T("packages watched"))
+		}
+
+		if FlagWatchNoSave {
+			return nil
+		}
+		if failed {
+			// same changes notified next time
+			return errors.New(tr.T("notification failed, versions not saved"))
+		}
+		return saveWatchState(filename, current)
+	},
+	Args: onlyFlags,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().StringSliceVar(&FlagWatchNotify, "notify", nil, tr.T("notify to")+": "+strings.Join(watchSinks, ", "))
+	watchCmd.Flags().BoolVarP(&FlagWatchNoSave, "no-save", "", FlagWatchNoSave, tr.T("do not save versions, report the same changes next time"))
+}